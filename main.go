@@ -2,211 +2,286 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
-	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+
+	"openapi-proto-transfer/transfer"
 )
 
-// Usage: go run openapi_to_proto.go <input-openapi.yaml> <output.proto>
+// usageText heads the -help output; the flag list follows it
+const usageText = `Usage: openapi-proto-transfer [flags] <input-openapi.yaml|-|URL>... <output.proto|dir/|->
+
+Converts OpenAPI 3 (or Swagger 2.0) specs into a proto3 file. Several
+inputs are merged first; "-" reads stdin and writes stdout, and an output
+ending in / with -group-by-tag writes one file per tag.
+
+Examples:
+  openapi-proto-transfer api.yaml api.proto
+  openapi-proto-transfer -package shop.v1 -go-package example.com/shop/v1 api.yaml api.proto
+  openapi-proto-transfer -pgv -no-http https://example.com/openapi.json -
+  openapi-proto-transfer -group-by-tag users.yaml orders.yaml protos/
+
+Flags:
+`
+
+// usage prints the synopsis, examples and every flag of fs with its
+// default. fs calls it for -h and -help, which exit 0.
+func usage(fs *flag.FlagSet) {
+	fmt.Fprint(fs.Output(), usageText)
+	fs.PrintDefaults()
+}
+
+// Usage: openapi-proto-transfer [flags] <input-openapi.yaml|-|URL>... <output.proto|dir/|->
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintln(os.Stderr, "Usage: openapi_to_proto <input-openapi.yaml> <output.proto>")
-		os.Exit(1)
+	os.Exit(cli(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli runs the command line args and returns the exit code. Bad flags and
+// missing arguments are usage errors, kept apart from the stage codes.
+func cli(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("openapi-proto-transfer", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(fs) }
+	opts := transfer.DefaultOptions()
+	fs.StringVar(&opts.PackageName, "package", "", "proto package name (default derived from info.title)")
+	fs.StringVar(&opts.GoPackage, "go-package", "", "emit option go_package with this import path")
+	fs.BoolVar(&opts.UnsignedFromMinimum, "unsigned", false, "map integers with minimum >= 0 to uint32/uint64")
+	fs.BoolVar(&opts.IncludeStandardHeaders, "include-standard-headers", false, "keep Authorization, Content-Type and similar header parameters in request messages")
+	fs.BoolVar(&opts.GroupByTag, "group-by-tag", false, "emit one service per operation tag; with a directory output, one file per tag")
+	fs.BoolVar(&opts.ErrorComments, "error-comments", false, "document 4xx/5xx responses as google.rpc.Status comments")
+	fs.BoolVar(&opts.SkipDeprecated, "skip-deprecated", false, "omit deprecated operations and unreferenced deprecated schemas")
+	fs.BoolVar(&opts.PGV, "pgv", false, "emit protoc-gen-validate rules from schema constraints")
+	fs.BoolVar(&opts.Protovalidate, "protovalidate", false, "emit buf protovalidate rules from schema constraints instead of PGV")
+	fs.BoolVar(&opts.PGVRequired, "pgv-required", false, "emit validation rules enforcing required message, string and bytes fields")
+	typeMap := fs.String("type-map", "", "YAML/JSON file of \"type/format\": \"ProtoType\" overrides")
+	contentTypes := fs.String("content-types", "", "comma-separated media type preference for bodies and responses (default application/json,application/*)")
+	successCodes := fs.String("success-codes", "", "comma-separated response code preference for rpc outputs (default 200,201,2xx,default)")
+	fs.BoolVar(&opts.FieldMask, "field-mask", false, "add a google.protobuf.FieldMask update_mask to PATCH requests")
+	fs.BoolVar(&opts.MethodSignature, "method-signature", false, "add google.api.method_signature options listing required request fields")
+	fs.BoolVar(&opts.StreamArrays, "stream-arrays", false, "stream the items of array responses instead of wrapping them in a list message")
+	fs.BoolVar(&opts.ReadWriteSplit, "read-write-split", false, "drop readOnly properties from requests and writeOnly properties from responses")
+	validate := fs.Bool("validate", true, "validate the spec before generating; -validate=false gives a best-effort proto for invalid specs")
+	fs.IntVar(&opts.Workers, "workers", 1, "write component schemas on this many goroutines")
+	fs.Func("indent", "indentation: \"tab\", a number of spaces, or a string of spaces and tabs (default 2 spaces)", func(v string) (err error) {
+		opts.Indent, err = indentFlag(v)
+		return err
+	})
+	noHTTP := fs.Bool("no-http", false, "omit google.api.http annotations (pure gRPC)")
+	var headers headerFlags
+	fs.Var(&headers, "header", "HTTP header for remote specs, sent only to the hosts of URL inputs, e.g. \"Authorization: Bearer TOKEN\" (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return exitUsage
+	}
+	opts.EmitHTTPAnnotations = !*noHTTP
+	if fs.NArg() < 2 {
+		usage(fs)
+		return exitUsage
 	}
-	inPath := os.Args[1]
-	outPath := os.Args[2]
+	opts.Warn = func(msg string) { fmt.Fprintln(stderr, "Warning:", msg) }
+	opts.ContentTypes = splitList(*contentTypes)
+	opts.SuccessCodes = splitList(*successCodes)
+	if *typeMap != "" {
+		m, err := readTypeMap(*typeMap)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitCode(err)
+		}
+		opts.TypeMap = m
+	}
+	paths := fs.Args()
+	c := command{stdin: stdin, stdout: stdout, stderr: stderr}
+	if err := c.run(opts, headers, *validate, paths[:len(paths)-1], paths[len(paths)-1]); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitCode(err)
+	}
+	return 0
+}
 
-	data, err := ioutil.ReadFile(inPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to read input file: %v\n", err)
-		os.Exit(2)
+// command holds the streams a run reads from and reports to
+type command struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// Exit codes, one per failure stage, kept stable for scripts and CI;
+// exitUsage also covers unknown flags and bad flag values
+const (
+	exitUsage    = 1
+	exitRead     = 2
+	exitParse    = 3
+	exitValidate = 4
+	exitWrite    = 5
+	exitGenerate = 6
+)
+
+// exitCode maps an error to the exit code of the stage that failed
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, transfer.ErrRead):
+		return exitRead
+	case errors.Is(err, transfer.ErrParse):
+		return exitParse
+	case errors.Is(err, transfer.ErrValidate):
+		return exitValidate
+	case errors.Is(err, transfer.ErrWrite):
+		return exitWrite
+	case errors.Is(err, transfer.ErrGenerate):
+		return exitGenerate
 	}
+	return exitUsage
+}
 
+// run converts the specs at inPaths, merged into one document, and writes
+// the result to outPath, validating first unless validate is false
+func (c command) run(opts transfer.Options, headers headerFlags, validate bool, inPaths []string, outPath string) error {
 	loader := openapi3.NewLoader()
 	loader.IsExternalRefsAllowed = true
-	doc, err := loader.LoadFromData(data)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to parse OpenAPI: %v\n", err)
-		os.Exit(3)
+	// headers often carry credentials, so external $ref hosts and redirect
+	// targets do not get them
+	hosts := make(map[string]bool)
+	for _, inPath := range inPaths {
+		if u, err := url.Parse(inPath); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			hosts[u.Host] = true
+		}
 	}
-	err = doc.Validate(context.Background())
+	client := &http.Client{Transport: headerTransport{headers: headers, hosts: hosts, base: http.DefaultTransport}}
+	loader.ReadFromURIFunc = openapi3.URIMapCache(openapi3.ReadFromURIs(openapi3.ReadFromHTTP(client), openapi3.ReadFromFile))
+
+	var docs []*openapi3.T
+	for _, inPath := range inPaths {
+		data, location, err := transfer.ReadSpec(loader, inPath, c.stdin)
+		if err != nil {
+			return err
+		}
+		doc, err := transfer.LoadSpec(loader, inPath, data, location)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	doc, err := transfer.Merge(docs...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "OpenAPI validation errors: %v\n", err)
-		os.Exit(4)
+		return err
 	}
-
-	proto := generateProto(doc)
-	if err := ioutil.WriteFile(outPath, []byte(proto), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to write proto file: %v\n", err)
-		os.Exit(5)
+	if validate {
+		if err := transfer.Validate(context.Background(), doc); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(c.stderr, "Warning: skipping OpenAPI validation")
 	}
-	fmt.Println("Wrote proto to", outPath)
-}
 
-// generateProto builds .proto text from OpenAPI document
-func generateProto(doc *openapi3.T) string {
-	var b strings.Builder
-	// Header
-	b.WriteString("syntax = \"proto3\";\n\n")
-	b.WriteString("package generated;\n")
-	b.WriteString("import \"google/api/annotations.proto\";\n")
-	b.WriteString("import \"google/protobuf/struct.proto\";\n")
-	b.WriteString("import \"google/protobuf/empty.proto\";\n\n")
-
-	// Schemas: enums and messages
-	for name, schemaRef := range doc.Components.Schemas {
-		schema := schemaRef.Value
-		// top-level enum
-		if len(schema.Enum) > 0 {
-			enumName := capitalize(name)
-			b.WriteString("enum " + enumName + " {\n")
-			for i, v := range schema.Enum {
-				constName := normalizeEnum(fmt.Sprint(v))
-				b.WriteString(fmt.Sprintf("  %s = %d;\n", constName, i))
-			}
-			b.WriteString("}\n\n")
+	// a directory output with -group-by-tag gets one file per tag
+	if info, err := os.Stat(outPath); opts.GroupByTag && (strings.HasSuffix(outPath, "/") || err == nil && info.IsDir()) {
+		files, err := transfer.GenerateFiles(doc, opts)
+		if err != nil {
+			return err
 		}
-		// message for object schemas
-		if len(schema.Properties) > 0 {
-			msgName := capitalize(name)
-			b.WriteString("message " + msgName + " {\n")
-			// inline enums for fields
-			for fld, fldRef := range schema.Properties {
-				if len(fldRef.Value.Enum) > 0 {
-					inline := capitalize(fld) + "Enum"
-					b.WriteString("  enum " + inline + " {\n")
-					for i, v := range fldRef.Value.Enum {
-						cn := normalizeEnum(fmt.Sprint(v))
-						b.WriteString(fmt.Sprintf("    %s = %d;\n", cn, i))
-					}
-					b.WriteString("  }\n")
-				}
-			}
-			// required lookup
-			required := make(map[string]bool)
-			for _, r := range schema.Required {
-				required[r] = true
-			}
-			// fields
-			idx := 1
-			for fld, fldRef := range schema.Properties {
-				opt := ""
-				if !required[fld] {
-					opt = "optional "
-				}
-				t := mapType(fld, fldRef)
-				b.WriteString(fmt.Sprintf("  %s%s %s = %d;\n", opt, t, fld, idx))
-				idx++
-			}
-			b.WriteString("}\n\n")
+		if err := transfer.WriteFiles(outPath, files); err != nil {
+			return err
 		}
+		fmt.Fprintln(c.stdout, "Wrote", len(files), "proto files to", outPath)
+		return nil
 	}
 
-	// Service
-	b.WriteString("service ApiService {\n")
-	// iterate paths with Map()
-	for path, pathItem := range doc.Paths.Map() {
-		for method, op := range pathItem.Operations() {
-			rpc := op.OperationID
-			if rpc == "" {
-				rpc = capitalize(strings.ToLower(method)) + formatPath(path)
-			}
-			reqType := "google.protobuf.Empty"
-			if op.RequestBody != nil && op.RequestBody.Value != nil {
-				for _, media := range op.RequestBody.Value.Content {
-					if media.Schema != nil {
-						reqType = resolveType(media.Schema)
-						break
-					}
-				}
-			}
-
-			// determine response type
-			respType := "google.protobuf.Empty"
-			for code, respRef := range op.Responses.Map() {
-				if strings.HasPrefix(code, "2") || code == "default" {
-					for _, media := range respRef.Value.Content {
-						if media.Schema != nil {
-							respType = resolveType(media.Schema)
-							break
-						}
-					}
-					break
-				}
-			}
-			// RPC
-			b.WriteString(fmt.Sprintf("  rpc %s(%s) returns (%s) {\n", rpc, reqType, respType))
-			b.WriteString("    option (google.api.http) = {\n")
-			b.WriteString(fmt.Sprintf("      %s: \"%s\"\n", strings.ToLower(method), path))
-			if method == "POST" || method == "PUT" || method == "PATCH" {
-				b.WriteString("      body: \"*\"\n")
-			}
-			b.WriteString("    };\n  }\n")
-		}
+	proto, err := transfer.Generate(doc, opts)
+	if err != nil {
+		return err
 	}
-	b.WriteString("}\n")
-	return b.String()
+	if err := transfer.WriteProto(outPath, proto, c.stdout); err != nil {
+		return err
+	}
+	if outPath != "-" {
+		fmt.Fprintln(c.stdout, "Wrote proto to", outPath)
+	}
+	return nil
 }
 
-func mapType(field string, ref *openapi3.SchemaRef) string {
-	if ref.Ref != "" {
-		parts := strings.Split(ref.Ref, "/")
-		return capitalize(parts[len(parts)-1])
-	}
-	s := ref.Value
-	if len(s.Enum) > 0 {
-		return capitalize(field) + "Enum"
-	}
-	tp := ""
-	if s.Type != nil && len(*s.Type) > 0 {
-		tp = (*s.Type)[0]
-	}
-	switch tp {
-	case "integer":
-		return "int32"
-	case "number":
-		return "double"
-	case "boolean":
-		return "bool"
-	case "string":
-		return "string"
-	case "array":
-		if s.Items != nil {
-			return "repeated " + mapType(field, s.Items)
+// indentFlag turns the -indent value into the indentation string;
+// anything that would not indent, such as "x", is rejected
+func indentFlag(v string) (string, error) {
+	if v == "tab" {
+		return "\t", nil
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		if n <= 0 {
+			return "", fmt.Errorf("want a positive number of spaces, got %d", n)
 		}
-	case "object":
-		return "map<string, string>"
+		return strings.Repeat(" ", n), nil
 	}
-	return "string"
+	if v == "" || strings.Trim(v, " \t") != "" {
+		return "", errors.New(`want "tab", a number of spaces, or spaces and tabs`)
+	}
+	return v, nil
 }
 
-func resolveType(ref *openapi3.SchemaRef) string {
-	if ref.Ref != "" {
-		parts := strings.Split(ref.Ref, "/")
-		return capitalize(parts[len(parts)-1])
+// splitList splits a comma-separated flag value, returning nil for ""
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
-	return "google.protobuf.Empty"
+	return list
 }
 
-func normalizeEnum(v string) string {
-	r := regexp.MustCompile("[^A-Za-z0-9]")
-	return r.ReplaceAllString(strings.ToUpper(v), "_")
+// readTypeMap loads a "type/format": "ProtoType" mapping from a YAML or
+// JSON file
+func readTypeMap(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: type map: %w", transfer.ErrRead, err)
+	}
+	var m map[string]string
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w: type map %s: %w", transfer.ErrParse, path, err)
+	}
+	return m, nil
 }
 
-func capitalize(s string) string {
-	if s == "" {
-		return s
+// headerFlags collects repeated -header "Name: value" flags
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerFlags) Set(v string) error {
+	if !strings.Contains(v, ":") {
+		return fmt.Errorf("header %q must be in \"Name: value\" form", v)
 	}
-	return strings.ToUpper(s[:1]) + s[1:]
+	*h = append(*h, v)
+	return nil
+}
+
+// headerTransport adds the configured headers to requests for hosts
+type headerTransport struct {
+	headers headerFlags
+	hosts   map[string]bool
+	base    http.RoundTripper
 }
 
-func formatPath(path string) string {
-	r := regexp.MustCompile(`[{}\\/\\-]`)
-	clean := r.ReplaceAllString(path, "_")
-	r2 := regexp.MustCompile(`_+`)
-	return r2.ReplaceAllString(clean, "_")
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.hosts[req.URL.Host] {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for _, h := range t.headers {
+		name, value, _ := strings.Cut(h, ":")
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return t.base.RoundTrip(req)
 }
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"openapi-proto-transfer/transfer"
+)
+
+func TestStdinInput(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.proto")
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{"-", out}, strings.NewReader(testSpec), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr.String())
+	}
+	proto, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(proto), "message User {") {
+		t.Errorf("output is missing message User:\n%s", proto)
+	}
+}
+
+// testSpec is a small spec the CLI tests feed in through each input kind
+const testSpec = `openapi: 3.0.3
+info: {title: Test, version: "1"}
+paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: string}
+`
+
+func TestStdoutOutput(t *testing.T) {
+	in := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(in, []byte(testSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{in, "-"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "// API: Test") || !strings.Contains(stdout.String(), "rpc GetUser(GetUserRequest) returns (User)") {
+		t.Errorf("stdout is not the proto:\n%s", stdout.String())
+	}
+	// the success line would corrupt piped output
+	if strings.Contains(stdout.String(), "Wrote proto") {
+		t.Errorf("stdout has the success line:\n%s", stdout.String())
+	}
+}
+
+func TestJSONAndYAMLParity(t *testing.T) {
+	jsonSpec := `{
+  "openapi": "3.0.3",
+  "info": {"title": "Test", "version": "1"},
+  "paths": {"/users/{id}": {"get": {
+    "operationId": "getUser",
+    "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+    "responses": {"200": {"description": "ok", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}}}
+  }}},
+  "components": {"schemas": {"User": {"type": "object", "properties": {"id": {"type": "string"}}}}}
+}`
+	var protos []string
+	for _, spec := range []string{testSpec, jsonSpec} {
+		doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+		if err != nil {
+			t.Fatalf("load spec: %v", err)
+		}
+		proto, err := transfer.Generate(doc, transfer.DefaultOptions())
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		protos = append(protos, proto)
+	}
+	if protos[0] != protos[1] {
+		t.Errorf("JSON output differs from YAML output:\n%s\nvs\n%s", protos[1], protos[0])
+	}
+}
+
+func TestURLInput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testSpec))
+	}))
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-header", "Authorization: Bearer secret", srv.URL + "/spec.yaml", "-"}
+	if code := cli(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "message User {") {
+		t.Errorf("output is missing message User:\n%s", stdout.String())
+	}
+	if code := cli([]string{srv.URL + "/spec.yaml", "-"}, nil, &stdout, &stderr); code != exitRead {
+		t.Errorf("exit code without the header = %d, want %d (exitRead)", code, exitRead)
+	}
+}
+
+func TestHeadersOnlyForSpecHost(t *testing.T) {
+	leaked := false
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			leaked = true
+		}
+		switch r.URL.Path {
+		case "/user.yaml":
+			w.Write([]byte("type: object\nproperties:\n  id: {type: string}\n"))
+		default:
+			w.Write([]byte(testSpec))
+		}
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved.yaml" {
+			http.Redirect(w, r, other.URL+"/spec.yaml", http.StatusFound)
+			return
+		}
+		w.Write([]byte(`openapi: 3.0.3
+info: {title: Test, version: "1"}
+paths: {}
+components:
+  schemas:
+    User: {$ref: '` + other.URL + `/user.yaml'}
+`))
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/spec.yaml", "/moved.yaml"} {
+		var stdout, stderr bytes.Buffer
+		args := []string{"-header", "Authorization: Bearer secret", srv.URL + path, "-"}
+		if code := cli(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("%s: exit code = %d; stderr:\n%s", path, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "message User {") {
+			t.Errorf("%s: output is missing message User:\n%s", path, stdout.String())
+		}
+	}
+	if leaked {
+		t.Error("-header value was sent to a host other than the spec's")
+	}
+}
+
+func TestTypeMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "types.yaml")
+	if err := os.WriteFile(path, []byte(`"string/uuid": UUID`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	typeMap, err := readTypeMap(path)
+	if err != nil {
+		t.Fatalf("readTypeMap: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`openapi: 3.0.3
+info: {title: Test, version: "1"}
+paths: {}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: string, format: uuid}
+`))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	opts := transfer.DefaultOptions()
+	opts.TypeMap = typeMap
+	proto, err := transfer.Generate(doc, opts)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.Contains(proto, "optional UUID id = 1;") {
+		t.Errorf("string/uuid was not mapped to UUID:\n%s", proto)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("%w: spec.yaml: no such file", transfer.ErrRead), exitRead},
+		{fmt.Errorf("%w YAML: bad indent", transfer.ErrParse), exitParse},
+		{fmt.Errorf("%w: schema %q: bad type", transfer.ErrValidate, "User"), exitValidate},
+		{fmt.Errorf("%w: out.proto: permission denied", transfer.ErrWrite), exitWrite},
+		{fmt.Errorf("%w: invalid go_package", transfer.ErrGenerate), exitGenerate},
+		{errors.New("something else"), exitUsage},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSkipValidation(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "spec.yaml")
+	// info.version is missing, which only validation rejects
+	spec := strings.Replace(testSpec, `info: {title: Test, version: "1"}`, "info: {title: Test}", 1)
+	if err := os.WriteFile(in, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.proto")
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{in, out}, nil, &stdout, &stderr); code != exitValidate {
+		t.Fatalf("exit code with validation = %d, want %d (exitValidate)", code, exitValidate)
+	}
+	if code := cli([]string{"-validate=false", in, out}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code without validation = %d; stderr:\n%s", code, stderr.String())
+	}
+	proto, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(proto), "message User {") {
+		t.Errorf("output is missing message User:\n%s", proto)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	for _, arg := range []string{"-h", "-help"} {
+		var stdout, stderr bytes.Buffer
+		if code := cli([]string{arg}, nil, &stdout, &stderr); code != 0 {
+			t.Errorf("%s exit code = %d, want 0", arg, code)
+		}
+		for _, want := range []string{"Usage:", "Examples:", "-package", "-go-package", "-pgv", "-header", "-group-by-tag", "-indent"} {
+			if !strings.Contains(stderr.String(), want) {
+				t.Errorf("%s output is missing %q:\n%s", arg, want, stderr.String())
+			}
+		}
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown flag", []string{"-bogus", "in.yaml", "out.proto"}},
+		{"bad flag value", []string{"-workers", "many", "in.yaml", "out.proto"}},
+		{"missing output", []string{"in.yaml"}},
+		{"indent word", []string{"-indent", "x", "in.yaml", "out.proto"}},
+		{"indent zero", []string{"-indent", "0", "in.yaml", "out.proto"}},
+		{"indent empty", []string{"-indent", "", "in.yaml", "out.proto"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := cli(tt.args, nil, &stdout, &stderr); got != exitUsage {
+				t.Errorf("exit code = %d, want %d (exitUsage); stderr:\n%s", got, exitUsage, stderr.String())
+			}
+		})
+	}
+}
+
+func TestIndentFlag(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"tab", "\t"},
+		{"4", "    "},
+		{"  ", "  "},
+		{"\t ", "\t "},
+	}
+	for _, tt := range tests {
+		got, err := indentFlag(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("indentFlag(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
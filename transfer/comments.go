@@ -0,0 +1,35 @@
+package transfer
+
+import (
+	"strings"
+)
+
+// commentWidth is the column long comments are wrapped at
+const commentWidth = 80
+
+// writeComment emits text as // lines at indent, keeping the author's line
+// breaks and wrapping long lines at commentWidth
+func writeComment(b *strings.Builder, indent, text string) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "*/", "* /"))
+	if text == "" {
+		return
+	}
+	width := commentWidth - len(indent) - 3
+	for _, line := range strings.Split(text, "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			b.WriteString(indent + "//\n")
+			continue
+		}
+		cur := words[0]
+		for _, w := range words[1:] {
+			if len(cur)+1+len(w) > width {
+				b.WriteString(indent + "// " + cur + "\n")
+				cur = w
+				continue
+			}
+			cur += " " + w
+		}
+		b.WriteString(indent + "// " + cur + "\n")
+	}
+}
@@ -0,0 +1,20 @@
+package transfer
+
+import (
+	"testing"
+)
+
+func TestMessageComments(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    User:
+      type: object
+      description: |-
+        A registered user.
+        Users own orders.
+      properties:
+        name: {type: string}
+`, DefaultOptions())
+	assertContains(t, out, "// A registered user.\n// Users own orders.\nmessage User {")
+}
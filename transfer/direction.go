@@ -0,0 +1,142 @@
+package transfer
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// directional returns the body or response schema an rpc carries with
+// readOnly properties dropped from input ("Input") or writeOnly ones from
+// output ("Output"). Inline objects are filtered in place; a $ref whose
+// properties need filtering gets a shared <Schema><kind> variant, named
+// by x-proto-type on the returned ref, and arrays are filtered item by
+// item. Nested messages keep their full shape. A variant reserves the
+// numbers of the fields it drops, so the ones it keeps are numbered as in
+// the full message.
+func (g *generator) directional(ref *openapi3.SchemaRef, kind string) *openapi3.SchemaRef {
+	if ref == nil || ref.Value == nil {
+		return ref
+	}
+	s := ref.Value
+	if schemaType(s) == "array" && s.Items != nil {
+		items := g.directional(s.Items, kind)
+		if items == s.Items {
+			return ref
+		}
+		copied := *s
+		copied.Items = items
+		return &openapi3.SchemaRef{Value: &copied}
+	}
+	filtered := directionalSchema(s, kind)
+	if filtered == nil {
+		return ref
+	}
+	if ref.Ref == "" {
+		return &openapi3.SchemaRef{Value: filtered}
+	}
+	full := typeName(refName(ref.Ref))
+	filtered.Extensions = maps.Clone(filtered.Extensions)
+	if reserved, ok := g.droppedNumbers(full, s, filtered); ok {
+		if filtered.Extensions == nil {
+			filtered.Extensions = make(map[string]any)
+		}
+		filtered.Extensions["x-proto-reserved"] = reserved
+	}
+	name := g.addMessage(full+kind, filtered)
+	// the variant keeps the $ref, so a body field is still named after the
+	// schema and validated as a message
+	variant := *filtered
+	variant.Extensions = maps.Clone(filtered.Extensions)
+	if variant.Extensions == nil {
+		variant.Extensions = make(map[string]any)
+	}
+	variant.Extensions["x-proto-type"] = name
+	return &openapi3.SchemaRef{Ref: ref.Ref, Value: &variant}
+}
+
+// directionalSchema returns a copy of s without the properties that do
+// not travel in kind's direction, or nil when every property does
+func directionalSchema(s *openapi3.Schema, kind string) *openapi3.Schema {
+	props, required := flattenSchema(s)
+	kept := make(openapi3.Schemas, len(props))
+	for name, prop := range props {
+		if p := prop.Value; p != nil && (kind == "Input" && p.ReadOnly || kind == "Output" && p.WriteOnly) {
+			continue
+		}
+		kept[name] = prop
+	}
+	if len(kept) == len(props) {
+		return nil
+	}
+	copied := *s
+	copied.AllOf = nil
+	copied.Properties = kept
+	copied.Required = nil
+	for _, name := range slices.Sorted(maps.Keys(required)) {
+		if kept[name] != nil {
+			copied.Required = append(copied.Required, name)
+		}
+	}
+	return &copied
+}
+
+// droppedNumbers returns the x-proto-reserved list of the variant
+// filtered, extended with the field numbers message msgName gives the
+// properties of s that filtered drops. It reports false when there is
+// nothing to reserve or the list is malformed, which writeMessage reports
+// for the full message.
+func (g *generator) droppedNumbers(msgName string, s, filtered *openapi3.Schema) ([]interface{}, bool) {
+	var reserved []interface{}
+	if v, ok := filtered.Extensions["x-proto-reserved"]; ok {
+		if reserved, ok = v.([]interface{}); !ok {
+			return nil, false
+		}
+	}
+	numbers := g.fieldNumbering(msgName, s)
+	var dropped []int
+	for _, fld := range slices.Sorted(maps.Keys(numbers)) {
+		if filtered.Properties[fld] == nil {
+			dropped = append(dropped, numbers[fld]...)
+		}
+	}
+	if len(dropped) == 0 {
+		return nil, false
+	}
+	slices.Sort(dropped)
+	reserved = slices.Clone(reserved)
+	for _, n := range dropped {
+		reserved = append(reserved, float64(n))
+	}
+	return reserved, true
+}
+
+// fieldNumbering returns the numbers writeMessage gives the properties of
+// an object schema, one per variant for a oneOf property
+func (g *generator) fieldNumbering(msgName string, schema *openapi3.Schema) map[string][]int {
+	props, _ := flattenSchema(schema)
+	fields := slices.Sorted(maps.Keys(props))
+	pinned := g.pinnedNumbers(msgName, props, fields)
+	var discard strings.Builder
+	next := fieldNumbers(pinned, g.writeReserved(&discard, msgName, schema, ""))
+	for range schema.OneOf {
+		next()
+	}
+	numbers := make(map[string][]int)
+	for _, fld := range fields {
+		if v := props[fld].Value; v != nil && len(v.OneOf) > 0 {
+			for range v.OneOf {
+				numbers[fld] = append(numbers[fld], next())
+			}
+			continue
+		}
+		if n, ok := pinned[fld]; ok {
+			numbers[fld] = []int{n}
+			continue
+		}
+		numbers[fld] = []int{next()}
+	}
+	return numbers
+}
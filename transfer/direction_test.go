@@ -0,0 +1,63 @@
+package transfer
+
+import (
+	"testing"
+)
+
+func TestReadWriteSplit(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ReadWriteSplit = true
+	out := generate(t, specHeader+`paths:
+  /users:
+    post:
+      operationId: createUser
+      requestBody:
+        content: {application/json: {schema: {$ref: '#/components/schemas/User'}}}
+      responses:
+        "200":
+          description: ok
+          content: {application/json: {schema: {$ref: '#/components/schemas/User'}}}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: string, readOnly: true}
+        name: {type: string}
+        password: {type: string, writeOnly: true}
+`, opts)
+	// the variants keep User's field numbers and reserve the dropped ones
+	assertContains(t, out,
+		"message User {\n  optional string id = 1;\n  optional string name = 2;\n  optional string password = 3;\n}",
+		"message UserOutput {\n  reserved 3;\n  optional string id = 1;\n  optional string name = 2;\n}",
+		"message UserInput {\n  reserved 1;\n  optional string name = 2;\n  optional string password = 3;\n}",
+		"rpc CreateUser(UserInput) returns (UserOutput)",
+	)
+}
+
+func TestReadWriteSplitKeepsPinnedNumbers(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ReadWriteSplit = true
+	out := generate(t, specHeader+`paths:
+  /users:
+    post:
+      operationId: createUser
+      requestBody:
+        content: {application/json: {schema: {$ref: '#/components/schemas/User'}}}
+      responses:
+        "204": {description: ok}
+components:
+  schemas:
+    User:
+      type: object
+      x-proto-reserved: [2]
+      properties:
+        id: {type: string, readOnly: true}
+        name: {type: string}
+        password: {type: string, x-proto-field: 7}
+`, opts)
+	assertContains(t, out,
+		"message User {\n  reserved 2;\n  optional string id = 1;\n  optional string name = 3;\n  optional string password = 7;\n}",
+		"message UserInput {\n  reserved 2, 1;\n  optional string name = 3;\n  optional string password = 7;\n}",
+	)
+}
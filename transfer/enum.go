@@ -0,0 +1,227 @@
+package transfer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// writeEnum emits an enum block with the proto3 zero value prepended.
+// Constants are prefixed with the enum name since proto3 enum values
+// share the enclosing scope. Integer enums whose values run 0..n-1 keep
+// their numbers, with the value 0 standing in for the zero member.
+// Constants named by x-enum-varnames carry their value as a trailing
+// comment, followed by the matching x-enum-descriptions entry.
+func (g *generator) writeEnum(b *strings.Builder, name string, s *openapi3.Schema, indent string) {
+	values := enumValues(s)
+	zero, consts := enumConstants(name, s)
+	named := enumVarnames(s) != nil
+	descriptions, _ := s.Extensions["x-enum-descriptions"].([]interface{})
+	numbers, alias := enumNumbers(values, zero == "")
+	b.WriteString(indent + "enum " + name + " {\n")
+	if alias {
+		b.WriteString(indent + g.indent + "option allow_alias = true;\n")
+	}
+	if zero != "" {
+		b.WriteString(fmt.Sprintf("%s%s%s = 0;\n", indent, g.indent, zero))
+	}
+	for i, constName := range consts {
+		num := numbers[i]
+		var comment []string
+		if named {
+			comment = append(comment, commentValue(values[i]))
+		}
+		// descriptions may be shorter than the enum or have gaps
+		if i < len(descriptions) {
+			if d, ok := descriptions[i].(string); ok && strings.TrimSpace(d) != "" {
+				comment = append(comment, strings.Join(strings.Fields(d), " "))
+			}
+		}
+		trailing := ""
+		if len(comment) > 0 {
+			trailing = " // " + strings.Join(comment, ": ")
+		}
+		b.WriteString(fmt.Sprintf("%s%s%s = %d;%s\n", indent, g.indent, constName, num, trailing))
+	}
+	b.WriteString(indent + "}\n")
+}
+
+// enumNumbers numbers enum values in order, from 0 when the values are
+// literal and from 1 otherwise. A repeated integer value, as legacy APIs
+// use for aliases, shares the number of its first occurrence; alias
+// reports whether that happened, which requires allow_alias.
+func enumNumbers(values []interface{}, literal bool) ([]int, bool) {
+	numbers := make([]int, len(values))
+	first := make(map[int64]int)
+	next, alias := 1, false
+	if literal {
+		next = 0
+	}
+	for i, v := range values {
+		if n, ok := enumInt(v); ok {
+			if prev, ok := first[n]; ok {
+				numbers[i], alias = prev, true
+				continue
+			}
+			first[n] = next
+		}
+		numbers[i] = next
+		next++
+	}
+	return numbers, alias
+}
+
+// enumConstants names the members of enum name: the prepended zero
+// member, "" when the values themselves start at 0, and one constant per
+// value in order, taken from x-enum-varnames when the schema has them
+func enumConstants(name string, s *openapi3.Schema) (string, []string) {
+	values := enumValues(s)
+	varnames := enumVarnames(s)
+	prefix := enumPrefix(name)
+	// identifiers may not start with a digit
+	if prefix != "" && unicode.IsDigit(rune(prefix[0])) {
+		prefix = "_" + prefix
+	}
+	zero := ""
+	if !contiguousFromZero(values) {
+		zero = prefix + "_UNSPECIFIED"
+	}
+	consts := make([]string, len(values))
+	seen := make(map[string]int)
+	// a spec value named UNSPECIFIED must not reuse the zero member's name
+	if zero != "" {
+		seen[zero] = 1
+	}
+	for i, v := range values {
+		// camelCase varnames split at word boundaries (NotFound -> NOT_FOUND)
+		if varnames != nil {
+			v = snakeCase(varnames[i])
+		}
+		constName := prefix + "_" + enumConstant(v)
+		// distinct values can normalize to the same identifier
+		if seen[constName]++; seen[constName] > 1 {
+			constName = fmt.Sprintf("%s_%d", constName, seen[constName])
+		}
+		consts[i] = constName
+	}
+	return zero, consts
+}
+
+// enumDefault explains how a field's default relates to the proto3 zero
+// value of its enum type, or returns "" when the default is not a member
+func enumDefault(name string, s *openapi3.Schema) string {
+	zero, consts := enumConstants(name, s)
+	for i, v := range enumValues(s) {
+		if fmt.Sprintf("%T:%v", v, v) != fmt.Sprintf("%T:%v", s.Default, s.Default) {
+			continue
+		}
+		if zero == "" && i == 0 {
+			return "the default " + consts[i] + " is the proto3 zero value"
+		}
+		if zero == "" {
+			zero = consts[0]
+		}
+		return "an unset field reads as " + zero + ", not the default " + consts[i]
+	}
+	return ""
+}
+
+// enumValues returns the values of an enum schema; a schema that is a
+// single 3.1 const becomes a one-member enum
+func enumValues(s *openapi3.Schema) []interface{} {
+	if len(s.Enum) > 0 {
+		return s.Enum
+	}
+	if v, ok := constValue(s); ok {
+		return []interface{}{v}
+	}
+	return nil
+}
+
+// constValue returns the 3.1 const keyword, which kin-openapi leaves in
+// the schema extensions
+func constValue(s *openapi3.Schema) (interface{}, bool) {
+	v, ok := s.Extensions["const"]
+	return v, ok
+}
+
+// enumVarnames returns the x-enum-varnames constant names of s, or nil
+// unless there is one string name per enum value
+func enumVarnames(s *openapi3.Schema) []string {
+	list, ok := s.Extensions["x-enum-varnames"].([]interface{})
+	if !ok || len(list) != len(enumValues(s)) {
+		return nil
+	}
+	names := make([]string, len(list))
+	for i, v := range list {
+		if names[i], ok = v.(string); !ok {
+			return nil
+		}
+	}
+	return names
+}
+
+// enumKey identifies an enum by its values and constant names so
+// structurally identical enums can be shared
+func enumKey(s *openapi3.Schema) string {
+	values := enumValues(s)
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%T:%v", v, v)
+	}
+	return strings.Join(append(parts, enumVarnames(s)...), "\x00")
+}
+
+// sharedEnum returns the top-level enum with exactly the values of s, if any
+func (g *generator) sharedEnum(s *openapi3.Schema) string {
+	return g.enums[enumKey(s)]
+}
+
+// enumConstant derives the constant suffix for an enum value; numbers are
+// not valid identifiers on their own so they become VALUE_<n>
+func enumConstant(v interface{}) string {
+	if n, ok := enumInt(v); ok {
+		if n < 0 {
+			return fmt.Sprintf("VALUE_MINUS_%d", -n)
+		}
+		return fmt.Sprintf("VALUE_%d", n)
+	}
+	c := strings.Trim(underscores.ReplaceAllString(normalizeEnum(fmt.Sprint(v)), "_"), "_")
+	if c == "" {
+		return "EMPTY"
+	}
+	return c
+}
+
+var underscores = regexp.MustCompile(`_+`)
+
+// enumInt reports whether an enum value is an integer; JSON decoding
+// yields float64 for every number
+func enumInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+// contiguousFromZero reports whether values are exactly the integers
+// 0..n-1 in order, so they can be used as proto enum numbers directly
+func contiguousFromZero(values []interface{}) bool {
+	for i, v := range values {
+		if n, ok := enumInt(v); !ok || n != int64(i) {
+			return false
+		}
+	}
+	return len(values) > 0
+}
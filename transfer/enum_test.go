@@ -0,0 +1,167 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnumZeroValue(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Status:
+      type: string
+      enum: [active, inactive]
+    Job:
+      type: object
+      properties:
+        state: {type: string, enum: [running, done]}
+`, Options{})
+	// the zero member comes first and the spec's values start at 1
+	assertContains(t, out,
+		"enum Status {\n  STATUS_UNSPECIFIED = 0;\n",
+		"ACTIVE = 1;\n",
+		"INACTIVE = 2;\n",
+		"  enum StateEnum {\n    STATE_ENUM_UNSPECIFIED = 0;\n",
+		"RUNNING = 1;\n",
+		"DONE = 2;\n",
+	)
+}
+
+func TestEnumValueNamedUnspecified(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Status:
+      type: string
+      enum: [UNSPECIFIED, ACTIVE]
+    Job:
+      type: object
+      properties:
+        state: {type: string, enum: [unspecified, running]}
+`, Options{})
+	assertContains(t, out,
+		"enum Status {\n  STATUS_UNSPECIFIED = 0;\n  STATUS_UNSPECIFIED_2 = 1;\n  STATUS_ACTIVE = 2;\n}",
+		"  enum StateEnum {\n    STATE_ENUM_UNSPECIFIED = 0;\n    STATE_ENUM_UNSPECIFIED_2 = 1;\n    STATE_ENUM_RUNNING = 2;\n  }",
+	)
+}
+
+func TestEnumConstantsArePrefixed(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Status:
+      type: string
+      enum: [ok, failed]
+    Job:
+      type: object
+      properties:
+        state: {type: string, enum: [running, failed]}
+`, Options{})
+	assertContains(t, out, "STATUS_OK = 1;", "STATUS_FAILED = 2;", "STATE_ENUM_RUNNING = 1;", "STATE_ENUM_FAILED = 2;")
+	// both enums have a failed value, each under its own prefix
+	for _, c := range []string{"STATUS_FAILED", "STATE_ENUM_FAILED"} {
+		if n := strings.Count(out, c+" ="); n != 1 {
+			t.Errorf("%s defined %d times, want 1:\n%s", c, n, out)
+		}
+	}
+}
+
+func TestIntegerEnum(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Priority: {type: integer, enum: [1, 2, 3]}
+`, DefaultOptions())
+	assertContains(t, out,
+		"enum Priority {\n  PRIORITY_UNSPECIFIED = 0;\n  PRIORITY_VALUE_1 = 1;\n  PRIORITY_VALUE_2 = 2;\n  PRIORITY_VALUE_3 = 3;\n}",
+	)
+}
+
+func TestEnumValuesStartingWithDigits(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Code: {type: string, enum: ["200 OK", "3rd"]}
+`, DefaultOptions())
+	assertContains(t, out, "  CODE_200_OK = 1;\n  CODE_3RD = 2;\n")
+}
+
+func TestSharedEnumReuse(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Status: {type: string, enum: [active, disabled]}
+    User:
+      type: object
+      properties:
+        owner: {$ref: '#/components/schemas/Status'}
+        status: {type: string, enum: [active, disabled]}
+`, DefaultOptions())
+	assertContains(t, out, "optional Status owner = 1;", "optional Status status = 2;")
+	if strings.Contains(out, "StatusEnum") {
+		t.Errorf("inline enum was not replaced by Status:\n%s", out)
+	}
+}
+
+func TestConstKeyword(t *testing.T) {
+	out := generateUnvalidated(t, `openapi: 3.1.0
+info: {title: Test, version: "1"}
+paths: {}
+components:
+  schemas:
+    Kind: {const: admin}
+    Thing:
+      type: object
+      properties:
+        version: {type: string, const: v1}
+`, DefaultOptions())
+	assertContains(t, out,
+		"enum Kind {\n  KIND_UNSPECIFIED = 0;\n  KIND_ADMIN = 1;\n}",
+		"  // const: \"v1\"\n  optional VersionEnum version = 1;",
+	)
+}
+
+func TestEnumVarnames(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Level:
+      type: integer
+      enum: [1, 2, 3]
+      x-enum-varnames: [Low, Medium, High]
+`, DefaultOptions())
+	assertContains(t, out,
+		"enum Level {\n  LEVEL_UNSPECIFIED = 0;\n",
+		"  LEVEL_LOW = 1;",
+		"  LEVEL_MEDIUM = 2;",
+		"  LEVEL_HIGH = 3;",
+	)
+}
+
+func TestEnumDescriptions(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Color:
+      type: string
+      enum: [r, g]
+      x-enum-descriptions: [The red one, The green one]
+`, DefaultOptions())
+	assertContains(t, out,
+		"  COLOR_R = 1; // The red one\n",
+		"  COLOR_G = 2; // The green one\n",
+	)
+}
+
+func TestEnumAllowAlias(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Code:
+      type: integer
+      enum: [1, 2, 2]
+      x-enum-varnames: [One, Two, Deux]
+`, DefaultOptions())
+	assertContains(t, out, "enum Code {\n  option allow_alias = true;\n  CODE_UNSPECIFIED = 0;\n", "  CODE_TWO = 2;", "  CODE_DEUX = 2;")
+}
@@ -0,0 +1,18 @@
+package transfer
+
+import "errors"
+
+// Errors returned by the library wrap one of these, so callers can tell
+// the failing stage apart with errors.Is
+var (
+	// ErrRead means the spec (or a file it needs) could not be read
+	ErrRead = errors.New("failed to read input file")
+	// ErrParse means the input is not a parseable OpenAPI or Swagger document
+	ErrParse = errors.New("failed to parse OpenAPI")
+	// ErrValidate means the document parsed but failed OpenAPI validation
+	ErrValidate = errors.New("OpenAPI validation errors")
+	// ErrGenerate means the document cannot be expressed as proto
+	ErrGenerate = errors.New("failed to generate proto")
+	// ErrWrite means the generated proto could not be written
+	ErrWrite = errors.New("failed to write proto file")
+)
@@ -0,0 +1,72 @@
+package transfer_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"openapi-proto-transfer/transfer"
+)
+
+func ExampleGenerate() {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: 3.0.3
+info: {title: Pets, version: "1"}
+paths:
+  /pets/{id}:
+    get:
+      operationId: getPet
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer, format: int64}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id]
+      properties:
+        id: {type: integer, format: int64}
+        name: {type: string}
+`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	proto, err := transfer.Generate(doc, transfer.DefaultOptions())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(proto)
+	// Output:
+	// // API: Pets
+	// // Version: 1
+	// // Generated by openapi-proto-transfer.
+	//
+	// syntax = "proto3";
+	//
+	// package pets;
+	// import "google/api/annotations.proto";
+	//
+	// message Pet {
+	//   int64 id = 1;
+	//   optional string name = 2;
+	// }
+	//
+	// message GetPetRequest {
+	//   int64 id = 1;
+	// }
+	//
+	// service ApiService {
+	//   // GET /pets/{id} -> 200
+	//   rpc GetPet(GetPetRequest) returns (Pet) {
+	//     option (google.api.http) = {
+	//       get: "/pets/{id}"
+	//     };
+	//   }
+	// }
+}
@@ -0,0 +1,218 @@
+package transfer
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// commonFile holds schemas shared by several tags, or used by none
+const commonFile = "common.proto"
+
+// GenerateFiles splits the proto by operation tag: each tag gets a file
+// such as users.proto with its service and the schemas only it uses, and
+// schemas shared between tags go into common.proto. Untagged operations go
+// into api.proto. The result maps file names to their contents.
+func GenerateFiles(doc *openapi3.T, opts Options) (map[string]string, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("%w: nil OpenAPI document", ErrGenerate)
+	}
+	doc = withComponents(doc)
+	if _, err := Generate(doc, opts); err != nil {
+		return nil, err
+	}
+	opts.GroupByTag = true
+	base := newGenerator(doc, opts)
+
+	// which files reference each component schema, directly or through
+	// other schemas
+	usedBy := make(map[string]map[string]bool)
+	paths := doc.Paths.Map()
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		pathItem := paths[path]
+		for _, op := range pathItem.Operations() {
+			if opts.SkipDeprecated && op.Deprecated {
+				continue
+			}
+			file := opFile(op)
+			refs := make(map[string]bool)
+			for _, ref := range operationSchemas(doc, mergeParams(doc, pathItem.Parameters, op.Parameters), op) {
+				collectRefs(doc, ref, refs)
+			}
+			for name := range refs {
+				if usedBy[name] == nil {
+					usedBy[name] = make(map[string]bool)
+				}
+				usedBy[name][file] = true
+			}
+		}
+	}
+	placed := make(map[string]string)
+	shared := make(map[string]bool)
+	for name := range doc.Components.Schemas {
+		placed[name] = commonFile
+		if len(usedBy[name]) == 1 {
+			for f := range usedBy[name] {
+				placed[name] = f
+			}
+		}
+		if placed[name] == commonFile && !base.skipped[name] {
+			collectRefs(doc, &openapi3.SchemaRef{Value: doc.Components.Schemas[name].Value}, shared)
+		}
+	}
+	// common.proto cannot import the tag files, so whatever it references
+	// lives there too
+	for name := range shared {
+		if _, ok := placed[name]; ok {
+			placed[name] = commonFile
+		}
+	}
+	schemasByFile := make(map[string][]string)
+	for _, name := range slices.Sorted(maps.Keys(doc.Components.Schemas)) {
+		if base.skipped[name] {
+			continue
+		}
+		file := placed[name]
+		base.schemaFiles[typeName(name)] = file
+		schemasByFile[file] = append(schemasByFile[file], name)
+	}
+	files := make(map[string]bool)
+	for _, pathItem := range paths {
+		for _, op := range pathItem.Operations() {
+			if !opts.SkipDeprecated || !op.Deprecated {
+				files[opFile(op)] = true
+			}
+		}
+	}
+
+	// a dry run of each service finds the request and response types it
+	// synthesizes, so the ones several files need go to common.proto
+	synthUsers := make(map[string]map[string]bool)
+	for file := range files {
+		probe := base.forFile(file)
+		probe.generated = make(map[string]string)
+		probe.comments = maps.Clone(base.comments)
+		probe.opts.Warn = nil
+		probe.writeService(doc)
+		for name := range probe.generated {
+			if synthUsers[name] == nil {
+				synthUsers[name] = make(map[string]bool)
+			}
+			synthUsers[name][file] = true
+		}
+	}
+	base.synthFiles = make(map[string]string)
+	for name, users := range synthUsers {
+		if len(users) > 1 {
+			base.synthFiles[name] = commonFile
+		}
+	}
+	if len(schemasByFile[commonFile]) > 0 || len(base.synthFiles) > 0 {
+		files[commonFile] = true
+	}
+
+	// common.proto is written last, once the other files have added the
+	// shared types to it
+	common := base.forFile(commonFile)
+	base.common = common
+	order := slices.Sorted(maps.Keys(files))
+	if files[commonFile] {
+		order = append(slices.DeleteFunc(order, func(f string) bool { return f == commonFile }), commonFile)
+	}
+	out := make(map[string]string)
+	for _, file := range order {
+		g := common
+		if file != commonFile {
+			g = base.forFile(file)
+		}
+		body := g.writeSchemas(schemasByFile[file])
+		svc := g.writeService(doc)
+		if g.err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrGenerate, g.err)
+		}
+		out[file] = g.header() + body + g.extra.String() + svc
+	}
+	return out, nil
+}
+
+// forFile returns a copy of g that writes the given split output file
+func (g *generator) forFile(file string) *generator {
+	f := *g
+	f.file = file
+	f.imports = make(map[string]bool)
+	f.extra = strings.Builder{}
+	return &f
+}
+
+// opFile names the file an operation is written to in split output
+func opFile(op *openapi3.Operation) string {
+	if len(op.Tags) == 0 {
+		return "api.proto"
+	}
+	return snakeCase(pascalCase(op.Tags[0])) + ".proto"
+}
+
+// operationSchemas lists the parameter, request and response schemas of an
+// operation
+func operationSchemas(doc *openapi3.T, params openapi3.Parameters, op *openapi3.Operation) []*openapi3.SchemaRef {
+	var refs []*openapi3.SchemaRef
+	for _, p := range params {
+		if p.Value != nil && p.Value.Schema != nil {
+			refs = append(refs, p.Value.Schema)
+		}
+	}
+	if body := requestBody(doc, op.RequestBody); body != nil {
+		for _, media := range body.Content {
+			if media.Schema != nil {
+				refs = append(refs, media.Schema)
+			}
+		}
+	}
+	for _, ref := range op.Responses.Map() {
+		resp := response(doc, ref)
+		if resp == nil {
+			continue
+		}
+		for _, media := range resp.Content {
+			if media.Schema != nil {
+				refs = append(refs, media.Schema)
+			}
+		}
+	}
+	return refs
+}
+
+// collectRefs adds the component schemas reachable from ref to seen
+func collectRefs(doc *openapi3.T, ref *openapi3.SchemaRef, seen map[string]bool) {
+	if ref == nil {
+		return
+	}
+	s := ref.Value
+	if ref.Ref != "" {
+		name := refName(ref.Ref)
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if c := doc.Components.Schemas[name]; c != nil {
+			s = c.Value
+		}
+	}
+	if s == nil {
+		return
+	}
+	for _, p := range s.Properties {
+		collectRefs(doc, p, seen)
+	}
+	for _, group := range []openapi3.SchemaRefs{s.AllOf, s.OneOf, s.AnyOf} {
+		for _, m := range group {
+			collectRefs(doc, m, seen)
+		}
+	}
+	collectRefs(doc, s.Items, seen)
+	collectRefs(doc, s.Not, seen)
+	collectRefs(doc, s.AdditionalProperties.Schema, seen)
+}
@@ -0,0 +1,126 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateFilesByTag(t *testing.T) {
+	files, err := GenerateFiles(loadSpec(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      tags: [users]
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+  /orders:
+    get:
+      operationId: listOrders
+      tags: [orders]
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Order'}
+components:
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+    Order: {type: object, properties: {buyer: {$ref: '#/components/schemas/User'}}}
+`), DefaultOptions())
+	if err != nil {
+		t.Fatalf("GenerateFiles: %v", err)
+	}
+	if len(files) != 3 {
+		t.Errorf("got %d files, want users.proto, orders.proto and common.proto", len(files))
+	}
+	assertContains(t, files[commonFile], "message User {")
+	assertContains(t, files["orders.proto"], `import "common.proto";`, "message Order {", "service OrdersService {")
+	assertContains(t, files["users.proto"], `import "common.proto";`, "service UsersService {")
+	if strings.Contains(files["users.proto"], "message Order {") {
+		t.Errorf("users.proto defines Order:\n%s", files["users.proto"])
+	}
+}
+
+func TestCommonReferencesStayInCommon(t *testing.T) {
+	files, err := GenerateFiles(loadSpec(t, specHeader+`paths:
+  /orders:
+    get:
+      operationId: listOrders
+      tags: [orders]
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Order'}
+components:
+  schemas:
+    Order: {type: object, properties: {id: {type: string}}}
+    Audit: {type: object, properties: {order: {$ref: '#/components/schemas/Order'}}}
+`), DefaultOptions())
+	if err != nil {
+		t.Fatalf("GenerateFiles: %v", err)
+	}
+	// Audit is used by no operation, so it goes to common.proto, which
+	// cannot import orders.proto back
+	assertContains(t, files[commonFile], "message Audit {", "message Order {")
+	if strings.Contains(files[commonFile], "import") {
+		t.Errorf("common.proto imports another file:\n%s", files[commonFile])
+	}
+	assertContains(t, files["orders.proto"], `import "common.proto";`, "returns (Order)")
+}
+
+func TestSharedSynthesizedTypesGoToCommon(t *testing.T) {
+	spec := specHeader + `paths:
+  /users:
+    get:
+      tags: [users]
+      operationId: listUsers
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/User'}}
+  /orders/{id}/users:
+    get:
+      tags: [orders]
+      operationId: listOrderUsers
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/User'}}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: string}
+`
+	files, err := GenerateFiles(loadSpec(t, spec), Options{})
+	if err != nil {
+		t.Fatalf("GenerateFiles: %v", err)
+	}
+	assertContains(t, files[commonFile], "message User {", "message ListUsersResponse {")
+	for _, file := range []string{"users.proto", "orders.proto"} {
+		out := files[file]
+		assertContains(t, out, `import "common.proto";`, "returns (ListUsersResponse)")
+		if strings.Contains(out, "message ListUsersResponse") {
+			t.Errorf("%s defines the shared ListUsersResponse:\n%s", file, out)
+		}
+	}
+	if strings.Contains(files["users.proto"], `import "orders.proto";`) {
+		t.Errorf("users.proto imports orders.proto:\n%s", files["users.proto"])
+	}
+	// a synthesized type only one file uses stays in it
+	assertContains(t, files["orders.proto"], "message ListOrderUsersRequest {")
+}
@@ -0,0 +1,285 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+)
+
+// ReadSpec reads the spec from path, from stdin when path is "-", or
+// through the loader when path is an http(s) URL, falling back to
+// openapi3.DefaultReadFromURI when the loader has no ReadFromURIFunc. The
+// returned location is set for remote specs so relative refs resolve
+// against it.
+func ReadSpec(loader *openapi3.Loader, path string, stdin io.Reader) ([]byte, *url.URL, error) {
+	data, location, err := readSpec(loader, path, stdin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrRead, err)
+	}
+	return data, location, nil
+}
+
+func readSpec(loader *openapi3.Loader, path string, stdin io.Reader) ([]byte, *url.URL, error) {
+	if path == "-" {
+		data, err := ioutil.ReadAll(stdin)
+		return data, nil, err
+	}
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		location, err := url.Parse(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		read := loader.ReadFromURIFunc
+		if read == nil {
+			read = openapi3.DefaultReadFromURI
+		}
+		data, err := read(loader, location)
+		return data, location, err
+	}
+	// the file location lets relative external refs resolve against the
+	// spec's own directory, as loader.LoadFromFile would
+	data, err := ioutil.ReadFile(path)
+	return data, &url.URL{Path: filepath.ToSlash(path)}, err
+}
+
+// LoadSpec parses an OpenAPI 3 document read from path, converting Swagger
+// 2.0 input first
+func LoadSpec(loader *openapi3.Loader, path string, data []byte, location *url.URL) (*openapi3.T, error) {
+	doc, err := loadDoc(loader, data, location)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %w", ErrParse, inputFormat(path, data), err)
+	}
+	return doc, nil
+}
+
+// swaggerPattern spots a Swagger 2.0 document without parsing it: a
+// top-level YAML swagger key, or a JSON "swagger": "2..." member
+var swaggerPattern = regexp.MustCompile(`(?m)^["']?swagger["']?\s*:|"swagger"\s*:\s*"2`)
+
+// loadDoc parses an OpenAPI 3 document, converting Swagger 2.0 input first.
+// The version sniff avoids decoding large OpenAPI 3 specs twice.
+func loadDoc(loader *openapi3.Loader, data []byte, location *url.URL) (*openapi3.T, error) {
+	if numericExclusivePattern.Match(data) {
+		var err error
+		if data, err = rewriteExclusiveBounds(data); err != nil {
+			return nil, err
+		}
+	}
+	var version struct {
+		Swagger string `json:"swagger"`
+	}
+	if !swaggerPattern.Match(data) || yaml.Unmarshal(data, &version) != nil || version.Swagger == "" {
+		if location != nil {
+			return loader.LoadFromDataWithPath(data, location)
+		}
+		return loader.LoadFromData(data)
+	}
+	var doc2 openapi2.T
+	if err := yaml.Unmarshal(data, &doc2); err != nil {
+		return nil, fmt.Errorf("swagger %s: %w", version.Swagger, err)
+	}
+	doc, err := openapi2conv.ToV3WithLoader(&doc2, loader, location)
+	if err != nil {
+		return nil, fmt.Errorf("converting swagger %s to OpenAPI 3: %w", version.Swagger, err)
+	}
+	return doc, nil
+}
+
+// numericExclusivePattern spots the OpenAPI 3.1 (JSON Schema 2020-12)
+// spelling of exclusiveMinimum/exclusiveMaximum, a number rather than a
+// flag on minimum/maximum
+var numericExclusivePattern = regexp.MustCompile(`["']?exclusiveM(?:in|ax)imum["']?\s*:\s*-?[0-9.]`)
+
+// rewriteExclusiveBounds turns numeric exclusiveMinimum/exclusiveMaximum
+// into the 3.0 form kin-openapi decodes: the bound moves to
+// minimum/maximum with the exclusive flag set, unless an inclusive bound
+// already in the schema is stricter. Only schema objects are rewritten,
+// so example and default values keep their keys. The result is JSON.
+func rewriteExclusiveBounds(data []byte) ([]byte, error) {
+	var tree interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	walkDocSchemas(tree)
+	return json.Marshal(tree)
+}
+
+// documentValues are the keys outside schemas whose values are data, not
+// more of the document
+var documentValues = map[string]bool{"example": true, "examples": true, "default": true, "enum": true, "const": true}
+
+// walkDocSchemas finds the schemas of a decoded document: the values of
+// schema keys and the members of components.schemas
+func walkDocSchemas(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			switch {
+			case documentValues[key] || strings.HasPrefix(key, "x-"):
+			case key == "schema":
+				walkSchema(child)
+			case key == "schemas":
+				if m, ok := child.(map[string]interface{}); ok {
+					for _, s := range m {
+						walkSchema(s)
+					}
+				}
+			default:
+				walkDocSchemas(child)
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			walkDocSchemas(child)
+		}
+	}
+}
+
+// subschemaKeys are the JSON Schema keywords holding one schema, a list of
+// them, or a map of them
+var (
+	subschemaKeys     = []string{"items", "additionalProperties", "not", "if", "then", "else", "contains", "propertyNames", "unevaluatedItems", "unevaluatedProperties"}
+	subschemaListKeys = []string{"allOf", "oneOf", "anyOf", "prefixItems"}
+	subschemaMapKeys  = []string{"properties", "patternProperties", "$defs", "dependentSchemas"}
+)
+
+// walkSchema rewrites the exclusive bounds of schema v and its subschemas
+func walkSchema(v interface{}) {
+	s, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	exclusiveBound(s, "exclusiveMinimum", "minimum", func(a, b float64) bool { return a >= b })
+	exclusiveBound(s, "exclusiveMaximum", "maximum", func(a, b float64) bool { return a <= b })
+	for _, key := range subschemaKeys {
+		walkSchema(s[key])
+	}
+	for _, key := range subschemaListKeys {
+		list, _ := s[key].([]interface{})
+		for _, child := range list {
+			walkSchema(child)
+		}
+	}
+	for _, key := range subschemaMapKeys {
+		m, _ := s[key].(map[string]interface{})
+		for _, child := range m {
+			walkSchema(child)
+		}
+	}
+}
+
+// exclusiveBound rewrites one numeric exclusive keyword of schema s;
+// stricter reports whether exclusive bound a is at least as tight as
+// inclusive bound b
+func exclusiveBound(s map[string]interface{}, exclusive, inclusive string, stricter func(a, b float64) bool) {
+	bound, ok := s[exclusive].(float64)
+	if !ok {
+		return
+	}
+	if current, ok := s[inclusive].(float64); ok && !stricter(bound, current) {
+		delete(s, exclusive)
+		return
+	}
+	s[inclusive] = bound
+	s[exclusive] = true
+}
+
+// inputFormat names the spec encoding for error messages, going by the
+// file extension and falling back to sniffing the content
+func inputFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "JSON"
+	case ".yaml", ".yml":
+		return "YAML"
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return "JSON"
+	}
+	return "YAML"
+}
+
+// Validate checks doc against the OpenAPI specification. The document
+// check stops at the first problem, so on failure each component schema
+// and operation is checked on its own and every problem is reported
+// under the schema name or operation it belongs to.
+func Validate(ctx context.Context, doc *openapi3.T) error {
+	// OpenAPI 3.1 makes paths optional, so a schemas-only spec is valid
+	if doc.Paths == nil && strings.HasPrefix(doc.OpenAPI, "3.1") {
+		d := *doc
+		d.Paths = openapi3.NewPaths()
+		doc = &d
+	}
+	err := doc.Validate(ctx)
+	if err == nil {
+		return nil
+	}
+	var located []error
+	if doc.Components != nil {
+		for _, name := range slices.Sorted(maps.Keys(doc.Components.Schemas)) {
+			if s := doc.Components.Schemas[name].Value; s != nil {
+				if err := s.Validate(ctx); err != nil {
+					located = append(located, fmt.Errorf("schema %q: %w", name, err))
+				}
+			}
+		}
+	}
+	if doc.Paths != nil {
+		paths := doc.Paths.Map()
+		for _, path := range slices.Sorted(maps.Keys(paths)) {
+			ops := paths[path].Operations()
+			for _, method := range slices.Sorted(maps.Keys(ops)) {
+				if err := ops[method].Validate(ctx); err != nil {
+					located = append(located, fmt.Errorf("%s %s: %w", method, path, err))
+				}
+			}
+		}
+	}
+	if len(located) > 0 {
+		err = errors.Join(located...)
+	}
+	return fmt.Errorf("%w: %w", ErrValidate, err)
+}
+
+// WriteProto writes the proto to path, or to stdout when path is "-"
+func WriteProto(path, proto string, stdout io.Writer) error {
+	var err error
+	if path == "-" {
+		_, err = io.WriteString(stdout, proto)
+	} else {
+		err = ioutil.WriteFile(path, []byte(proto), 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWrite, err)
+	}
+	return nil
+}
+
+// WriteFiles writes each generated file into dir, creating it if needed
+func WriteFiles(dir string, files map[string]string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%w: %w", ErrWrite, err)
+	}
+	for name, proto := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(proto), 0644); err != nil {
+			return fmt.Errorf("%w: %w", ErrWrite, err)
+		}
+	}
+	return nil
+}
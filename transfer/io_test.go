@@ -0,0 +1,139 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestInputFormat(t *testing.T) {
+	tests := []struct {
+		path, data, want string
+	}{
+		{"spec.json", "openapi: 3.0.3", "JSON"},
+		{"spec.YML", "{}", "YAML"},
+		{"-", ` {"openapi": "3.0.3"}`, "JSON"},
+		{"-", "openapi: 3.0.3", "YAML"},
+	}
+	for _, tt := range tests {
+		if got := inputFormat(tt.path, []byte(tt.data)); got != tt.want {
+			t.Errorf("inputFormat(%q, %q) = %s, want %s", tt.path, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestSwagger2Input(t *testing.T) {
+	doc, err := LoadSpec(openapi3.NewLoader(), "spec.yaml", []byte(`swagger: "2.0"
+info: {title: Test, version: "1"}
+paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+      produces: [application/json]
+      parameters:
+        - {name: id, in: path, required: true, type: string}
+      responses:
+        "200":
+          description: ok
+          schema: {$ref: '#/definitions/User'}
+definitions:
+  User:
+    type: object
+    properties:
+      id: {type: string}
+`), nil)
+	if err != nil {
+		t.Fatalf("LoadSpec: %v", err)
+	}
+	proto, err := Generate(doc, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	assertContains(t, proto, "syntax = \"proto3\";", "message User {", "returns (User)")
+}
+
+func TestValidateNamesSchema(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(specHeader + `paths: {}
+components:
+  schemas:
+    Good: {type: object, properties: {id: {type: string}}}
+    User: {type: object, properties: {age: {type: integer, format: int32, default: old}}}
+`))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	err = Validate(context.Background(), doc)
+	if !errors.Is(err, ErrValidate) {
+		t.Fatalf("Validate = %v, want ErrValidate", err)
+	}
+	if !strings.Contains(err.Error(), `schema "User"`) || strings.Contains(err.Error(), `schema "Good"`) {
+		t.Errorf("error does not point at schema User: %v", err)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	data := []byte(syntheticSpec(500))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := LoadSpec(openapi3.NewLoader(), "bench.yaml", data, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReadSpecURLWithPlainLoader(t *testing.T) {
+	spec := specHeader + "paths: {}\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(spec))
+	}))
+	defer srv.Close()
+
+	loader := openapi3.NewLoader()
+	data, location, err := ReadSpec(loader, srv.URL+"/spec.yaml", nil)
+	if err != nil {
+		t.Fatalf("ReadSpec: %v", err)
+	}
+	if string(data) != spec {
+		t.Errorf("ReadSpec data = %q, want %q", data, spec)
+	}
+	if location == nil || location.Path != "/spec.yaml" {
+		t.Errorf("ReadSpec location = %v, want the spec URL", location)
+	}
+}
+
+func TestExclusiveBoundsOnlyInSchemas(t *testing.T) {
+	spec := `openapi: 3.1.0
+info: {title: Test, version: "1"}
+paths: {}
+components:
+  schemas:
+    Range:
+      type: object
+      properties:
+        low: {type: number, exclusiveMinimum: 0}
+        high: {type: number, exclusiveMaximum: 10, maximum: 5}
+      example: {low: 1, exclusiveMinimum: 5}
+`
+	doc, err := LoadSpec(openapi3.NewLoader(), "spec.yaml", []byte(spec), nil)
+	if err != nil {
+		t.Fatalf("LoadSpec: %v", err)
+	}
+	props := doc.Components.Schemas["Range"].Value.Properties
+	if low := props["low"].Value; low.Min == nil || *low.Min != 0 || !low.ExclusiveMin {
+		t.Errorf("low: min %v exclusive %t, want > 0", low.Min, low.ExclusiveMin)
+	}
+	// the inclusive maximum is stricter, so it stays
+	if high := props["high"].Value; high.Max == nil || *high.Max != 5 || high.ExclusiveMax {
+		t.Errorf("high: max %v exclusive %t, want <= 5", high.Max, high.ExclusiveMax)
+	}
+	example, _ := doc.Components.Schemas["Range"].Value.Example.(map[string]interface{})
+	if _, ok := example["minimum"]; ok || example["exclusiveMinimum"] != float64(5) {
+		t.Errorf("example rewritten: %v", example)
+	}
+}
@@ -0,0 +1,101 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Merge combines several documents into one: component schemas, paths and
+// tags are pooled, with info and servers taken from the first document.
+// A schema name or path operation defined differently in two documents is
+// an error; identical duplicates are accepted.
+func Merge(docs ...*openapi3.T) (*openapi3.T, error) {
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%w: nothing to merge", ErrParse)
+	}
+	if len(docs) == 1 {
+		return docs[0], nil
+	}
+	merged := &openapi3.T{
+		OpenAPI: docs[0].OpenAPI,
+		Info:    docs[0].Info,
+		Servers: docs[0].Servers,
+		Paths:   openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas:       make(openapi3.Schemas),
+			Parameters:    make(openapi3.ParametersMap),
+			RequestBodies: make(openapi3.RequestBodies),
+			Responses:     make(openapi3.ResponseBodies),
+		},
+	}
+	c := merged.Components
+	for _, doc := range docs {
+		if dc := doc.Components; dc != nil {
+			if err := mergeComponents(c.Schemas, dc.Schemas, "schema"); err != nil {
+				return nil, err
+			}
+			if err := mergeComponents(c.Parameters, dc.Parameters, "parameter"); err != nil {
+				return nil, err
+			}
+			if err := mergeComponents(c.RequestBodies, dc.RequestBodies, "request body"); err != nil {
+				return nil, err
+			}
+			if err := mergeComponents(c.Responses, dc.Responses, "response"); err != nil {
+				return nil, err
+			}
+		}
+		for _, tag := range doc.Tags {
+			if merged.Tags.Get(tag.Name) == nil {
+				merged.Tags = append(merged.Tags, tag)
+			}
+		}
+		if doc.Paths == nil {
+			continue
+		}
+		paths := doc.Paths.Map()
+		for _, path := range slices.Sorted(maps.Keys(paths)) {
+			item := merged.Paths.Value(path)
+			if item == nil {
+				item = &openapi3.PathItem{}
+				merged.Paths.Set(path, item)
+			}
+			src := paths[path]
+			for _, p := range src.Parameters {
+				if p.Value == nil || item.Parameters.GetByInAndName(p.Value.In, p.Value.Name) == nil {
+					item.Parameters = append(item.Parameters, p)
+				}
+			}
+			ops := src.Operations()
+			for _, method := range slices.Sorted(maps.Keys(ops)) {
+				if prev := item.GetOperation(method); prev != nil && !sameJSON(prev, ops[method]) {
+					return nil, fmt.Errorf("%w: %s %s is defined differently in two inputs", ErrValidate, method, path)
+				}
+				item.SetOperation(method, ops[method])
+			}
+		}
+	}
+	return merged, nil
+}
+
+// mergeComponents copies src into dst, failing when a name is already
+// taken by a different definition
+func mergeComponents[M ~map[string]V, V any](dst, src M, kind string) error {
+	for _, name := range slices.Sorted(maps.Keys(src)) {
+		if prev, ok := dst[name]; ok && !sameJSON(prev, src[name]) {
+			return fmt.Errorf("%w: %s %q is defined differently in two inputs", ErrValidate, kind, name)
+		}
+		dst[name] = src[name]
+	}
+	return nil
+}
+
+// sameJSON reports whether a and b serialize to the same JSON
+func sameJSON(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
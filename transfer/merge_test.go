@@ -0,0 +1,42 @@
+package transfer
+
+import (
+	"testing"
+)
+
+func TestMergeDisjointSpecs(t *testing.T) {
+	users := loadSpec(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200": {description: ok, content: {application/json: {schema: {$ref: '#/components/schemas/User'}}}}
+components:
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+`)
+	orders := loadSpec(t, specHeader+`paths:
+  /orders:
+    get:
+      operationId: listOrders
+      responses:
+        "200": {description: ok, content: {application/json: {schema: {$ref: '#/components/schemas/Order'}}}}
+components:
+  schemas:
+    Order: {type: object, properties: {total: {type: number}}}
+`)
+	doc, err := Merge(users, orders)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	out, err := Generate(doc, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	assertContains(t, out,
+		"message Order {",
+		"message User {",
+		`get: "/orders"`,
+		`get: "/users"`,
+	)
+}
@@ -0,0 +1,435 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// isMessage reports whether a schema is emitted as a proto message
+func isMessage(schema *openapi3.Schema) bool {
+	props, _ := flattenSchema(schema)
+	return len(props) > 0 || len(schema.OneOf) > 0
+}
+
+// inlineObject returns the inline object schema behind a property, looking
+// through arrays (reported as repeated), or nil when the property is a $ref
+// or not an object
+func inlineObject(ref *openapi3.SchemaRef) (*openapi3.Schema, bool) {
+	if ref == nil || ref.Ref != "" || ref.Value == nil {
+		return nil, false
+	}
+	s := ref.Value
+	if _, ok := s.Extensions["x-proto-type"].(string); ok || refAlias(ref) != nil {
+		return nil, false
+	}
+	if s.Items != nil && schemaType(s) == "array" {
+		obj, _ := inlineObject(s.Items)
+		return obj, obj != nil
+	}
+	if isMessage(s) && len(s.OneOf) == 0 {
+		return s, false
+	}
+	return nil, false
+}
+
+// refAlias returns the $ref wrapped by a property that is only
+// {allOf: [$ref]}, the usual way to add nullable or a description next to
+// a reference, or nil for any other schema. Such a property names the
+// referenced type rather than inlining a copy of it.
+func refAlias(ref *openapi3.SchemaRef) *openapi3.SchemaRef {
+	if ref == nil || ref.Ref != "" || ref.Value == nil {
+		return nil
+	}
+	s := ref.Value
+	if len(s.AllOf) != 1 || s.AllOf[0].Ref == "" || len(s.Properties) > 0 || len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return nil
+	}
+	return s.AllOf[0]
+}
+
+// inlineEnum returns the enum declared inline on a property or on its array
+// items, or nil when there is none or the property is a $ref
+func inlineEnum(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref.Ref != "" || ref.Value == nil {
+		return nil
+	}
+	if schemaType(ref.Value) == "array" && ref.Value.Items != nil {
+		return inlineEnum(ref.Value.Items)
+	}
+	if len(enumValues(ref.Value)) > 0 {
+		return ref.Value
+	}
+	return nil
+}
+
+// writeMessage emits a message for an object schema. Inline object
+// properties become nested messages named after the field, prefixed with
+// the parent name when that would shadow a top-level type. An inline
+// object that contains itself, through allOf, refers back to the message
+// already being written for it.
+func (g *generator) writeMessage(b *strings.Builder, msgName string, schema *openapi3.Schema, indent string) {
+	g.expanding[schema] = msgName
+	defer delete(g.expanding, schema)
+	props, required := flattenSchema(schema)
+	defer func(scope string) { g.scope = scope }(g.scope)
+	g.scope = msgName
+	writeComment(b, indent, schema.Description)
+	b.WriteString(indent + "message " + msgName + " {\n")
+	inner := indent + g.indent
+	reserved := g.writeReserved(b, msgName, schema, inner)
+	fields := slices.Sorted(maps.Keys(props))
+	// properties such as createdAt and created_at would share a field,
+	// and item and Item a nested type
+	owners := make(map[string]string)
+	nestedNames := make(map[string]bool)
+	for _, fld := range fields {
+		if prev, ok := owners[fieldName(fld)]; ok {
+			g.fail(fmt.Errorf("%s: properties %q and %q both map to field %s", msgName, prev, fld, fieldName(fld)))
+		}
+		owners[fieldName(fld)] = fld
+		if e := inlineEnum(props[fld]); e != nil && g.sharedEnum(e) == "" {
+			nestedNames[typeName(fld)+"Enum"] = true
+		}
+	}
+	// nested messages and inline enums for fields
+	nested := make(map[string]string)
+	for _, fld := range fields {
+		fldRef := props[fld]
+		if obj, _ := inlineObject(fldRef); obj != nil {
+			if name, ok := g.expanding[obj]; ok {
+				nested[fld] = name
+				continue
+			}
+			nestedName := typeName(fld)
+			if g.types[nestedName] || nestedName == msgName {
+				nestedName = msgName + nestedName
+			}
+			for base, i := nestedName, 2; nestedNames[nestedName]; i++ {
+				nestedName = fmt.Sprintf("%s%d", base, i)
+			}
+			nestedNames[nestedName] = true
+			nested[fld] = nestedName
+			g.writeMessage(b, nestedName, obj, inner)
+			continue
+		}
+		if e := inlineEnum(fldRef); e != nil && g.sharedEnum(e) == "" {
+			g.writeEnum(b, typeName(fld)+"Enum", e, inner)
+		}
+	}
+	// inline enum variants of oneofs
+	variantEnums := make(map[*openapi3.SchemaRef]string)
+	if len(schema.OneOf) > 0 {
+		g.writeVariantEnums(b, msgName, schema, nestedNames, variantEnums, inner)
+	}
+	for _, fld := range fields {
+		if v := props[fld].Value; v != nil && len(v.OneOf) > 0 {
+			g.writeVariantEnums(b, fld, v, nestedNames, variantEnums, inner)
+		}
+	}
+	// fields
+	pinned := g.pinnedNumbers(msgName, props, fields)
+	for fld, n := range pinned {
+		if reserved[n] {
+			g.fail(fmt.Errorf("%s.%s: x-proto-field %d is reserved", msgName, fld, n))
+		}
+	}
+	next := fieldNumbers(pinned, reserved)
+	if len(schema.OneOf) > 0 {
+		g.writeOneof(b, msgName, schema, variantEnums, next, inner)
+	}
+	for _, fld := range fields {
+		fldRef := props[fld]
+		if fldRef.Value != nil && len(fldRef.Value.OneOf) > 0 {
+			g.writeOneof(b, fld, fldRef.Value, variantEnums, next, inner)
+			continue
+		}
+		idx, ok := pinned[fld]
+		if !ok {
+			idx = next()
+		}
+		var t string
+		message := false
+		if n, ok := nested[fld]; ok {
+			t = n
+			_, repeated := inlineObject(fldRef)
+			if repeated {
+				t = "repeated " + n
+			}
+			message = !repeated
+		} else {
+			t = g.mapType(fld, fldRef)
+			target := fldRef
+			if alias := refAlias(fldRef); alias != nil {
+				target = alias
+			}
+			message = target.Ref != "" && target.Value != nil && isMessage(target.Value) ||
+				strings.HasPrefix(t, "google.protobuf.")
+		}
+		// optional is not allowed on repeated and map fields
+		opt := ""
+		optional := !required[fld] || (fldRef.Value != nil && nullable(fldRef.Value))
+		if optional && !strings.HasPrefix(t, "repeated ") && !strings.HasPrefix(t, "map<") {
+			opt = "optional "
+		}
+		name := fieldName(fld)
+		var fieldOpts []string
+		if rules := g.fieldRules(t, fldRef, !optional, message); rules != "" {
+			fieldOpts = append(fieldOpts, rules)
+		}
+		deprecated := fldRef.Ref == "" && fldRef.Value != nil && fldRef.Value.Deprecated
+		if deprecated {
+			fieldOpts = append(fieldOpts, "deprecated = true")
+		}
+		writeFieldComment(b, inner, fldRef)
+		// without a validation backend, item bounds are only documented
+		if s := fldRef.Value; !g.opts.PGV && !g.opts.Protovalidate && fldRef.Ref == "" && s != nil && schemaType(s) == "array" {
+			if bounds := itemBounds(s); len(bounds) > 0 {
+				writeComment(b, inner, strings.ReplaceAll(strings.Join(bounds, ", "), "_", " "))
+			}
+		}
+		if e := inlineEnum(fldRef); e != nil && e.Default != nil && !strings.HasPrefix(t, "repeated ") {
+			writeComment(b, inner, enumDefault(t, e))
+		}
+		for _, c := range g.comments[fldRef] {
+			b.WriteString(inner + "// " + c + "\n")
+		}
+		if deprecated {
+			b.WriteString(inner + "// Deprecated.\n")
+		}
+		b.WriteString(fmt.Sprintf("%s%s%s %s = %d%s;\n", inner, opt, g.use(t), name, idx, fieldOptions(fld, name, fieldOpts)))
+	}
+	b.WriteString(indent + "}\n")
+}
+
+// fieldOptions renders the bracketed options of a field: json_name first
+// when the proto name differs from the original property name, then the
+// given options in order, or "" when there are none
+func fieldOptions(original, name string, opts []string) string {
+	if name != original {
+		opts = append([]string{"json_name = " + protoString(original)}, opts...)
+	}
+	if len(opts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(opts, ", ") + "]"
+}
+
+// writeFieldComment documents a property with its description, default,
+// const, example and multipleOf, which neither validation backend has a
+// rule for. $ref properties are skipped since the description belongs to
+// the referenced message.
+func writeFieldComment(b *strings.Builder, indent string, ref *openapi3.SchemaRef) {
+	if ref.Ref != "" || ref.Value == nil {
+		return
+	}
+	writeComment(b, indent, ref.Value.Description)
+	if ref.Value.Default != nil {
+		writeComment(b, indent, "default: "+commentValue(ref.Value.Default))
+	}
+	if v, ok := constValue(ref.Value); ok {
+		writeComment(b, indent, "const: "+commentValue(v))
+	}
+	if ref.Value.Example != nil {
+		writeComment(b, indent, "example: "+commentValue(ref.Value.Example))
+	}
+	if ref.Value.MultipleOf != nil {
+		writeComment(b, indent, "multiple of "+strconv.FormatFloat(*ref.Value.MultipleOf, 'g', -1, 64))
+	}
+}
+
+// commentValue renders a default or example value as JSON
+func commentValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// flattenSchema merges the schema's own properties with those of its allOf
+// members, later members overriding earlier ones, and returns them with
+// the combined required lookup
+func flattenSchema(schema *openapi3.Schema) (openapi3.Schemas, map[string]bool) {
+	return flatten(schema, make(map[*openapi3.Schema]bool))
+}
+
+// flatten implements flattenSchema, skipping allOf members already being
+// merged so cyclic allOf chains terminate
+func flatten(schema *openapi3.Schema, seen map[*openapi3.Schema]bool) (openapi3.Schemas, map[string]bool) {
+	seen[schema] = true
+	props := make(openapi3.Schemas)
+	required := make(map[string]bool)
+	for _, member := range schema.AllOf {
+		if member.Value == nil || seen[member.Value] {
+			continue
+		}
+		mp, mr := flatten(member.Value, seen)
+		maps.Copy(props, mp)
+		maps.Copy(required, mr)
+	}
+	maps.Copy(props, schema.Properties)
+	for _, r := range schema.Required {
+		required[r] = true
+	}
+	return props, required
+}
+
+// pinnedNumbers collects the field numbers fixed by x-proto-field on the
+// message's properties, failing generation on invalid or duplicate pins
+func (g *generator) pinnedNumbers(msgName string, props openapi3.Schemas, fields []string) map[string]int {
+	pinned := make(map[string]int)
+	owner := make(map[int]string)
+	for _, fld := range fields {
+		s := props[fld].Value
+		if s == nil || len(s.OneOf) > 0 {
+			continue
+		}
+		v, ok := s.Extensions["x-proto-field"]
+		if !ok {
+			continue
+		}
+		n, ok := v.(float64)
+		if !ok || n < 1 || n != float64(int(n)) {
+			g.fail(fmt.Errorf("%s.%s: x-proto-field must be a positive integer, got %v", msgName, fld, v))
+			continue
+		}
+		if prev, dup := owner[int(n)]; dup {
+			g.fail(fmt.Errorf("%s: fields %s and %s both pin x-proto-field %d", msgName, prev, fld, int(n)))
+			continue
+		}
+		owner[int(n)] = fld
+		pinned[fld] = int(n)
+	}
+	return pinned
+}
+
+// writeReserved emits reserved statements for the numbers and names listed
+// in the schema's x-proto-reserved extension and returns the numbers
+func (g *generator) writeReserved(b *strings.Builder, msgName string, schema *openapi3.Schema, indent string) map[int]bool {
+	v, ok := schema.Extensions["x-proto-reserved"]
+	if !ok {
+		return nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		g.fail(fmt.Errorf("%s: x-proto-reserved must be a list, got %v", msgName, v))
+		return nil
+	}
+	numbers := make(map[int]bool)
+	var nums, names []string
+	for _, item := range list {
+		switch item := item.(type) {
+		case float64:
+			if item < 1 || item != float64(int(item)) {
+				g.fail(fmt.Errorf("%s: x-proto-reserved number must be a positive integer, got %v", msgName, item))
+				continue
+			}
+			numbers[int(item)] = true
+			nums = append(nums, strconv.Itoa(int(item)))
+		case string:
+			names = append(names, fmt.Sprintf("%q", item))
+		default:
+			g.fail(fmt.Errorf("%s: x-proto-reserved entries must be field numbers or names, got %v", msgName, item))
+		}
+	}
+	if len(nums) > 0 {
+		b.WriteString(indent + "reserved " + strings.Join(nums, ", ") + ";\n")
+	}
+	if len(names) > 0 {
+		b.WriteString(indent + "reserved " + strings.Join(names, ", ") + ";\n")
+	}
+	return numbers
+}
+
+// fieldNumbers returns a function handing out the lowest field numbers
+// not taken by pinned fields or reserved
+func fieldNumbers(pinned map[string]int, reserved map[int]bool) func() int {
+	taken := maps.Clone(reserved)
+	if taken == nil {
+		taken = make(map[int]bool)
+	}
+	for _, n := range pinned {
+		taken[n] = true
+	}
+	idx := 0
+	return func() int {
+		idx++
+		for taken[idx] {
+			idx++
+		}
+		return idx
+	}
+}
+
+// writeOneof emits a oneof block with one field per variant of s.OneOf,
+// numbered by next. Variants named in the discriminator mapping take the
+// mapping key as field name. Arrays and maps cannot be oneof members, so
+// they travel as google.protobuf.ListValue and google.protobuf.Struct, and
+// inline enums use the nested enum writeVariantEnums wrote for them.
+func (g *generator) writeOneof(b *strings.Builder, name string, s *openapi3.Schema, enums map[*openapi3.SchemaRef]string, next func() int, indent string) {
+	b.WriteString(indent + "oneof " + fieldName(name) + " {\n")
+	seen := make(map[string]int)
+	for _, v := range s.OneOf {
+		t, ok := enums[v]
+		if !ok {
+			t = repeatedItem(g.mapType(name, v))
+		}
+		fld := fieldName(t)
+		opts := ""
+		if v.Ref == "" {
+			fld = snakeCase(t) + "_value"
+		} else if key := discriminatorKey(s.Discriminator, v.Ref); key != "" {
+			fld = fieldName(key)
+			opts = fieldOptions(key, fld, nil)
+		}
+		// variants of the same type would share a field name
+		if seen[fld]++; seen[fld] > 1 {
+			fld = fmt.Sprintf("%s_%d", fld, seen[fld])
+		}
+		b.WriteString(fmt.Sprintf("%s%s%s %s = %d%s;\n", indent, g.indent, g.use(t), fld, next(), opts))
+	}
+	b.WriteString(indent + "}\n")
+}
+
+// writeVariantEnums emits a nested enum for each inline enum variant of
+// s.OneOf that no top-level enum matches, named after the oneof and kept
+// clear of the names in taken, and records the names in enums
+func (g *generator) writeVariantEnums(b *strings.Builder, name string, s *openapi3.Schema, taken map[string]bool, enums map[*openapi3.SchemaRef]string, indent string) {
+	for _, v := range s.OneOf {
+		e := v.Value
+		if v.Ref != "" || e == nil || len(enumValues(e)) == 0 || g.sharedEnum(e) != "" {
+			continue
+		}
+		if _, ok := e.Extensions["x-proto-type"].(string); ok {
+			continue
+		}
+		enumName := typeName(name) + "Enum"
+		for base, i := enumName, 2; taken[enumName]; i++ {
+			enumName = fmt.Sprintf("%s%d", base, i)
+		}
+		taken[enumName] = true
+		enums[v] = enumName
+		g.writeEnum(b, enumName, e, indent)
+	}
+}
+
+// discriminatorKey returns the mapping key that selects the schema at ref,
+// or "" when the discriminator has no mapping for it. Mapping values may be
+// full references or bare schema names.
+func discriminatorKey(d *openapi3.Discriminator, ref string) string {
+	if d == nil {
+		return ""
+	}
+	for _, key := range slices.Sorted(maps.Keys(d.Mapping)) {
+		if v := d.Mapping[key]; v == ref || v == refName(ref) {
+			return key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,300 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOneOfProperty(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Cat: {type: object, properties: {meow: {type: boolean}}}
+    Dog: {type: object, properties: {bark: {type: boolean}}}
+    Owner:
+      type: object
+      properties:
+        pet:
+          oneOf:
+            - $ref: '#/components/schemas/Cat'
+            - $ref: '#/components/schemas/Dog'
+`, Options{})
+	assertContains(t, out, "message Owner {\n  oneof pet {\n    Cat cat = 1;\n    Dog dog = 2;\n  }\n}")
+}
+
+func TestOneOfInlineVariants(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Owner:
+      type: object
+      properties:
+        pet:
+          oneOf:
+            - {type: string}
+            - {type: string, maxLength: 3}
+            - {type: array, items: {type: string}}
+            - {type: object, additionalProperties: {type: integer}}
+            - {type: string, enum: [cat, dog]}
+`, Options{})
+	// repeated and map fields cannot be oneof members
+	assertContains(t, out,
+		"  enum PetEnum {\n    PET_ENUM_UNSPECIFIED = 0;\n    PET_ENUM_CAT = 1;\n    PET_ENUM_DOG = 2;\n  }\n",
+		"  oneof pet {\n"+
+			"    string string_value = 1;\n"+
+			"    string string_value_2 = 2;\n"+
+			"    google.protobuf.ListValue google_protobuf_list_value_value = 3;\n"+
+			"    google.protobuf.Struct google_protobuf_struct_value = 4;\n"+
+			"    PetEnum pet_enum_value = 5;\n"+
+			"  }",
+		`import "google/protobuf/struct.proto";`,
+	)
+}
+
+func TestAllOfIsFlattened(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Animal: {type: object, properties: {name: {type: string}}}
+    Dog:
+      allOf:
+        - $ref: '#/components/schemas/Animal'
+        - type: object
+          properties:
+            breed: {type: string}
+`, Options{})
+	assertContains(t, out, "message Dog {\n  optional string breed = 1;\n  optional string name = 2;\n}")
+}
+
+func TestNestedInlineObjects(t *testing.T) {
+	out := generate(t, propertySpec(`
+          type: object
+          properties:
+            geo:
+              type: object
+              properties:
+                lat: {type: number}`), Options{})
+	assertContains(t, out,
+		"message Thing {\n  message Value {\n    message Geo {\n      optional double lat = 1;\n    }\n    optional Geo geo = 1;\n  }\n  optional Value value = 1;\n}",
+	)
+}
+
+func TestNoOptionalOnRepeatedOrMap(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{"{type: array, items: {type: string}}", "  repeated string value = 1;"},
+		{"{type: object, additionalProperties: {type: string}}", "  map<string, string> value = 1;"},
+		{"{type: string}", "  optional string value = 1;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			assertContains(t, generate(t, propertySpec(tt.schema), Options{}), tt.want)
+		})
+	}
+}
+
+func TestFieldComments(t *testing.T) {
+	out := generate(t, propertySpec("{type: string, description: Display name}"), DefaultOptions())
+	assertContains(t, out, "  // Display name\n  optional string value = 1;")
+}
+
+func TestPinnedFieldNumbers(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Job:
+      type: object
+      properties:
+        a: {type: string, x-proto-field: 5}
+        b: {type: string}
+        c: {type: string, x-proto-field: 10}
+        d: {type: string}
+        e: {type: string}
+        f: {type: string}
+        g: {type: string}
+        h: {type: string}
+`, DefaultOptions())
+	// pinned fields keep 5 and 10, the rest fill 1-4 and then 6 onwards
+	assertContains(t, out,
+		"optional string a = 5;",
+		"optional string b = 1;",
+		"optional string c = 10;",
+		"optional string d = 2;",
+		"optional string e = 3;",
+		"optional string f = 4;",
+		"optional string g = 6;",
+		"optional string h = 7;",
+	)
+}
+
+func TestReservedFieldNumbers(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Pet:
+      type: object
+      x-proto-reserved: [2, 4]
+      properties:
+        a: {type: string}
+        b: {type: string}
+        c: {type: string}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message Pet {\n  reserved 2, 4;\n",
+		"optional string a = 1;",
+		"optional string b = 3;",
+		"optional string c = 5;",
+	)
+}
+
+func TestDiscriminatorOneof(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Cat: {type: object, properties: {meow: {type: boolean}}}
+    Dog: {type: object, properties: {bark: {type: boolean}}}
+    Owner:
+      type: object
+      properties:
+        pet:
+          oneOf:
+            - {$ref: '#/components/schemas/Cat'}
+            - {$ref: '#/components/schemas/Dog'}
+          discriminator:
+            propertyName: kind
+            mapping:
+              feline: '#/components/schemas/Cat'
+              canine: '#/components/schemas/Dog'
+`, DefaultOptions())
+	assertContains(t, out, "  oneof pet {\n    Cat feline = 1;\n    Dog canine = 2;\n  }")
+}
+
+func TestAllOfCycleTerminates(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Node:
+      allOf:
+        - {$ref: '#/components/schemas/Base'}
+        - type: object
+          properties:
+            name: {type: string}
+    Base:
+      type: object
+      properties:
+        parent: {$ref: '#/components/schemas/Node'}
+`, DefaultOptions())
+	assertContains(t, out, "message Node {", "optional Node parent = ")
+}
+
+func TestFieldOptionsCombined(t *testing.T) {
+	opts := DefaultOptions()
+	opts.PGVRequired = true
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Account:
+      type: object
+      required: [userName]
+      properties:
+        userName: {type: string, deprecated: true}
+`, opts)
+	assertContains(t, out, `string user_name = 1 [json_name = "userName", (validate.rules).string = {min_len: 1}, deprecated = true];`)
+}
+
+func TestDefaultComments(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Page:
+      type: object
+      properties:
+        size: {type: integer, default: 10}
+        order: {type: string, enum: [asc, desc], default: desc}
+`, DefaultOptions())
+	assertContains(t, out,
+		"  // default: 10\n  optional int32 size = 2;",
+		"  // default: \"desc\"\n  // an unset field reads as ORDER_ENUM_UNSPECIFIED, not the default\n  // ORDER_ENUM_DESC\n  optional OrderEnum order = 1;",
+	)
+}
+
+func TestMultipleOfComment(t *testing.T) {
+	out := generate(t, propertySpec("{type: integer, multipleOf: 5}"), DefaultOptions())
+	assertContains(t, out, "  // multiple of 5\n  optional int32 value = 1;")
+}
+
+func TestSelfReferenceTerminates(t *testing.T) {
+	tests := []struct {
+		name string
+		prop string
+		want string
+	}{
+		{"allOf ref", `{allOf: [{$ref: '#/components/schemas/Node'}]}`, "optional Node meta = 1;"},
+		{"nullable allOf ref", `{allOf: [{$ref: '#/components/schemas/Node'}], nullable: true}`, "optional Node meta = 1;"},
+		{"array of allOf ref", `{type: array, items: {allOf: [{$ref: '#/components/schemas/Node'}]}}`, "repeated Node meta = 1;"},
+		{"allOf ref with own properties", `{allOf: [{$ref: '#/components/schemas/Node'}, {properties: {x: {type: string}}}]}`, "optional Meta meta = 1;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := specHeader + `paths: {}
+components:
+  schemas:
+    Node:
+      type: object
+      properties:
+        meta: ` + tt.prop + `
+        name: {type: string}
+`
+			out := generate(t, spec, Options{})
+			assertContains(t, out, tt.want)
+			if n := strings.Count(out, "message "); n > 2 {
+				t.Errorf("got %d messages, want at most 2:\n%s", n, out)
+			}
+		})
+	}
+}
+
+func TestCollidingPropertyNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		props string
+		want  string
+	}{
+		{"case", "item: {type: object, properties: {a: {type: string}}}\n        Item: {type: object, properties: {b: {type: string}}}", `properties "Item" and "item" both map to field item`},
+		{"separator", "createdAt: {type: string}\n        created_at: {type: string}", `properties "createdAt" and "created_at" both map to field created_at`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := specHeader + `paths: {}
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        ` + tt.props + "\n"
+			_, err := Generate(loadSpec(t, spec), Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Generate error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+func TestNestedMessageNamesAreUnique(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        status: {type: string, enum: [on, off]}
+        statusEnum: {type: object, properties: {a: {type: string}}}
+`, Options{})
+	assertContains(t, out,
+		"  enum StatusEnum {",
+		"  message StatusEnum2 {",
+		"optional StatusEnum status = 1;",
+		"optional StatusEnum2 status_enum = 2 [json_name = \"statusEnum\"];",
+	)
+}
@@ -0,0 +1,139 @@
+package transfer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// enumPrefix turns an enum name like StatusEnum into STATUS_ENUM
+func enumPrefix(name string) string {
+	return strings.ToUpper(snakeCase(name))
+}
+
+// snakeCase converts camelCase/PascalCase identifiers to snake_case,
+// keeping acronym runs together (userID -> user_id, HTTPServer -> http_server)
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			b.WriteRune('_')
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	r := regexp.MustCompile(`_+`)
+	return r.ReplaceAllString(b.String(), "_")
+}
+
+func normalizeEnum(v string) string {
+	r := regexp.MustCompile("[^A-Za-z0-9]")
+	return r.ReplaceAllString(strings.ToUpper(v), "_")
+}
+
+// protoKeywords are identifiers protoc rejects or misparses as names
+var protoKeywords = map[string]bool{
+	"syntax": true, "edition": true, "import": true, "weak": true, "public": true,
+	"package": true, "option": true, "message": true, "enum": true, "service": true,
+	"rpc": true, "returns": true, "stream": true, "oneof": true, "map": true,
+	"repeated": true, "optional": true, "required": true, "reserved": true,
+	"extensions": true, "extend": true, "group": true, "to": true, "max": true,
+	"true": true, "false": true, "inf": true, "nan": true,
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true,
+	"uint64": true, "sint32": true, "sint64": true, "fixed32": true, "fixed64": true,
+	"sfixed32": true, "sfixed64": true, "bool": true, "string": true, "bytes": true,
+}
+
+// escapeKeyword appends an underscore to names that collide with proto keywords
+func escapeKeyword(s string) string {
+	if protoKeywords[s] {
+		return s + "_"
+	}
+	return s
+}
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// typeName derives a message or enum name from a schema name. Names that
+// are not proto identifiers, like order-line or User.Profile, are
+// PascalCased from their alphanumeric runs (OrderLine, UserProfile).
+func typeName(s string) string {
+	if identPattern.MatchString(s) {
+		return escapeKeyword(capitalize(s))
+	}
+	name := pascalCase(s)
+	if name == "" {
+		name = "Unnamed"
+	}
+	if unicode.IsDigit(rune(name[0])) {
+		name = "_" + name
+	}
+	return name
+}
+
+// fieldName derives a proto field name from a property name
+func fieldName(s string) string {
+	return escapeKeyword(snakeCase(s))
+}
+
+// pascalCase joins the alphanumeric runs of s into a PascalCase
+// identifier, e.g. "user accounts" -> UserAccounts
+func pascalCase(s string) string {
+	var b strings.Builder
+	for _, part := range regexp.MustCompile(`[^A-Za-z0-9]+`).Split(s, -1) {
+		b.WriteString(capitalize(part))
+	}
+	return b.String()
+}
+
+// packageName sanitizes a title like "Order API" into a proto package
+// identifier (order_api), returning "" when nothing usable remains
+func packageName(title string) string {
+	r := regexp.MustCompile(`[^a-z0-9_]`)
+	pkg := strings.Trim(r.ReplaceAllString(snakeCase(title), "_"), "_")
+	if pkg == "" || unicode.IsDigit(rune(pkg[0])) {
+		return ""
+	}
+	return escapeKeyword(pkg)
+}
+
+// plural naively pluralizes an English type name (Foo -> Foos, Entry -> Entries)
+func plural(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + "es"
+	case len(lower) > 1 && strings.HasSuffix(lower, "y") && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return s[:len(s)-1] + "ies"
+	}
+	return s + "s"
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// formatPath turns a path into the PascalCase tail of an rpc name
+// (/users/{id} -> UsersId, /order-lines/ -> OrderLines)
+func formatPath(path string) string {
+	return pascalCase(path)
+}
+
+// refName returns the component name a $ref points at, undoing JSON
+// pointer escapes
+func refName(ref string) string {
+	parts := strings.Split(ref, "/")
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(parts[len(parts)-1])
+}
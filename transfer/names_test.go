@@ -0,0 +1,83 @@
+package transfer
+
+import (
+	"testing"
+)
+
+func TestFieldNamesAreSnakeCase(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        createdAt: {type: string}
+        UserName: {type: string}
+        already_snake: {type: string}
+`, Options{})
+	assertContains(t, out,
+		` created_at = `,
+		` user_name = `,
+		` already_snake = `,
+	)
+}
+
+func TestReservedWordsAreEscaped(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    service:
+      type: object
+      properties:
+        message: {type: string}
+`, Options{})
+	assertContains(t, out, "message Service {", `optional string message_ = 1 [json_name = "message"];`)
+}
+
+func TestPackageNameFromTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Order API", "order_api"},
+		{"Pet-Store v2", "pet_store_v2"},
+		{"  ", "generated"},
+		{"3D Prints", "generated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			spec := "openapi: 3.0.3\ninfo: {title: \"" + tt.title + "\", version: \"1\"}\npaths: {}\ncomponents: {schemas: {}}\n"
+			assertContains(t, generate(t, spec, DefaultOptions()), "\npackage "+tt.want+";\n")
+		})
+	}
+}
+
+func TestSanitizedSchemaNames(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    order-line: {type: object, properties: {qty: {type: integer}}}
+    User.Profile: {type: object, properties: {line: {$ref: '#/components/schemas/order-line'}}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message OrderLine {",
+		"message UserProfile {\n  optional OrderLine line = 1;\n}",
+	)
+}
+
+func TestFormatPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/users", "Users"},
+		{"/users/{id}/orders", "UsersIdOrders"},
+		{"/user-groups/{groupId}", "UserGroupsGroupId"},
+		{"/users/", "Users"},
+		{"/", ""},
+	}
+	for _, tt := range tests {
+		if got := formatPath(tt.path); got != tt.want {
+			t.Errorf("formatPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,657 @@
+package transfer
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// defaultService holds operations without a tag, or every operation when
+// tag grouping is off
+const defaultService = "ApiService"
+
+// writeService emits the service blocks, one rpc per operation. With
+// GroupByTag each operation goes to a service named after its first tag.
+func (g *generator) writeService(doc *openapi3.T) string {
+	services := make(map[string]*strings.Builder)
+	// iterate paths and methods in sorted order so RPCs keep their place
+	paths := doc.Paths.Map()
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		pathItem := paths[path]
+		ops := pathItem.Operations()
+		for _, method := range slices.Sorted(maps.Keys(ops)) {
+			op := ops[method]
+			if g.file != "" && opFile(op) != g.file || g.opts.SkipDeprecated && op.Deprecated {
+				continue
+			}
+			name := g.serviceName(op)
+			if services[name] == nil {
+				services[name] = &strings.Builder{}
+			}
+			params := mergeParams(doc, pathItem.Parameters, op.Parameters)
+			g.writeRPC(services[name], path, method, op, params)
+		}
+	}
+	if len(services) == 0 && g.file == "" {
+		services[defaultService] = &strings.Builder{}
+	}
+	var svc strings.Builder
+	for i, name := range slices.Sorted(maps.Keys(services)) {
+		if i > 0 {
+			svc.WriteString("\n")
+		}
+		svc.WriteString("service " + name + " {\n")
+		svc.WriteString(services[name].String())
+		svc.WriteString("}\n")
+	}
+	return svc.String()
+}
+
+// serviceName picks the service an operation belongs to
+func (g *generator) serviceName(op *openapi3.Operation) string {
+	if !g.opts.GroupByTag || len(op.Tags) == 0 {
+		return defaultService
+	}
+	return pascalCase(op.Tags[0]) + "Service"
+}
+
+// writeRPC emits a single rpc with its google.api.http binding
+func (g *generator) writeRPC(svc *strings.Builder, path, method string, op *openapi3.Operation, params openapi3.Parameters) {
+	rpc := g.rpcs[op]
+	g.operation = method + " " + path
+	bodyMedia, body := "", (*openapi3.SchemaRef)(nil)
+	if rb := requestBody(g.doc, op.RequestBody); rb != nil {
+		bodyMedia, body = g.mediaSchema(rb.Content)
+	}
+	if g.opts.ReadWriteSplit {
+		body = g.directional(body, "Input")
+	}
+	reqType, bodyField, reqSchema := "", "*", (*openapi3.Schema)(nil)
+	if g.opts.FieldMask && method == "PATCH" {
+		reqType, bodyField, reqSchema = g.patchRequestType(rpc, body, params)
+	} else {
+		reqType, bodyField, reqSchema = g.requestType(rpc, bodyMedia, body, params)
+	}
+	reqType = g.use(reqType)
+	code, resp := g.successResponse(op)
+	respType, streamed, responseBody := g.responseType(rpc, code, resp)
+	respType = g.use(respType)
+	in1, in2, in3 := g.indent, strings.Repeat(g.indent, 2), strings.Repeat(g.indent, 3)
+	writeRPCComment(svc, in1, path, method, op, code, bodyMedia)
+	if g.opts.ErrorComments {
+		g.writeErrorComments(svc, op)
+	}
+	if op.Deprecated {
+		svc.WriteString(in1 + "//\n" + in1 + "// Deprecated.\n")
+	}
+	// RPC
+	reqStream, respStream := g.streaming(op, method, path)
+	if streamed {
+		respStream = "stream "
+	}
+	signature := fmt.Sprintf("%srpc %s(%s%s) returns (%s%s)", in1, rpc, reqStream, reqType, respStream, respType)
+	var methodSignature []string
+	if g.opts.MethodSignature && reqSchema != nil {
+		methodSignature = requiredFields(reqSchema)
+	}
+	if !g.opts.EmitHTTPAnnotations && !op.Deprecated && len(methodSignature) == 0 {
+		svc.WriteString(signature + ";\n")
+		return
+	}
+	svc.WriteString(signature + " {\n")
+	if op.Deprecated {
+		svc.WriteString(in2 + "option deprecated = true;\n")
+	}
+	if len(methodSignature) > 0 {
+		g.imports["google/api/client.proto"] = true
+		svc.WriteString(fmt.Sprintf("%soption (google.api.method_signature) = \"%s\";\n", in2, strings.Join(methodSignature, ",")))
+	}
+	if g.opts.EmitHTTPAnnotations {
+		g.imports["google/api/annotations.proto"] = true
+		svc.WriteString(in2 + "option (google.api.http) = {\n")
+		svc.WriteString(fmt.Sprintf("%s%s: \"%s\"\n", in3, strings.ToLower(method), bindPath(path)))
+		if method == "POST" || method == "PUT" || method == "PATCH" {
+			svc.WriteString(fmt.Sprintf("%sbody: \"%s\"\n", in3, bodyField))
+		}
+		if responseBody != "" {
+			svc.WriteString(fmt.Sprintf("%sresponse_body: \"%s\"\n", in3, responseBody))
+		}
+		for _, binding := range g.additionalBindings(op, method, path) {
+			in4 := strings.Repeat(g.indent, 4)
+			svc.WriteString(in3 + "additional_bindings {\n")
+			svc.WriteString(fmt.Sprintf("%s%s: \"%s\"\n", in4, binding[0], bindPath(binding[1])))
+			if binding[0] == "post" || binding[0] == "put" || binding[0] == "patch" {
+				svc.WriteString(fmt.Sprintf("%sbody: \"%s\"\n", in4, bodyField))
+			}
+			svc.WriteString(in3 + "}\n")
+		}
+		svc.WriteString(in2 + "};\n")
+	}
+	svc.WriteString(in1 + "}\n")
+}
+
+// additionalBindings reads the operation's x-additional-bindings, a list
+// of {method, path} objects, as lowercase method and path pairs; entries
+// that are not a usable HTTP rule are skipped with a warning
+func (g *generator) additionalBindings(op *openapi3.Operation, method, path string) [][2]string {
+	ext, ok := op.Extensions["x-additional-bindings"]
+	if !ok {
+		return nil
+	}
+	list, ok := ext.([]interface{})
+	if !ok {
+		g.warn("%s %s: x-additional-bindings must be a list of {method, path} objects", method, path)
+		return nil
+	}
+	var bindings [][2]string
+	for i, entry := range list {
+		m, _ := entry.(map[string]interface{})
+		verb, _ := m["method"].(string)
+		target, _ := m["path"].(string)
+		verb = strings.ToLower(verb)
+		switch verb {
+		case "get", "put", "post", "delete", "patch":
+		default:
+			g.warn("%s %s: x-additional-bindings[%d] needs a method of get, put, post, delete or patch", method, path, i)
+			continue
+		}
+		if !strings.HasPrefix(target, "/") {
+			g.warn("%s %s: x-additional-bindings[%d] needs a path starting with /", method, path, i)
+			continue
+		}
+		bindings = append(bindings, [2]string{verb, target})
+	}
+	return bindings
+}
+
+// streaming returns the "stream " prefixes of the rpc request and
+// response as set by the operation's x-streaming extension: server,
+// client or bidi
+func (g *generator) streaming(op *openapi3.Operation, method, path string) (string, string) {
+	mode, ok := op.Extensions["x-streaming"]
+	if !ok {
+		return "", ""
+	}
+	switch mode {
+	case "server":
+		return "", "stream "
+	case "client":
+		return "stream ", ""
+	case "bidi":
+		return "stream ", "stream "
+	}
+	g.warn("%s %s: unknown x-streaming value %v, expected server, client or bidi", method, path, mode)
+	return "", ""
+}
+
+// rpcNames assigns every operation an rpc name: its operationId in
+// PascalCase (get-user -> GetUser), or one derived from method and path.
+// Names that collide, such as those of /a-b and /a_b, get a numeric
+// suffix in path and method order, with a warning.
+func (g *generator) rpcNames(doc *openapi3.T) map[*openapi3.Operation]string {
+	names := make(map[*openapi3.Operation]string)
+	used := make(map[string]bool)
+	paths := doc.Paths.Map()
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		ops := paths[path].Operations()
+		for _, method := range slices.Sorted(maps.Keys(ops)) {
+			op := ops[method]
+			rpc := pascalCase(op.OperationID)
+			if rpc == "" {
+				rpc = capitalize(strings.ToLower(method)) + formatPath(path)
+			} else if unicode.IsDigit(rune(rpc[0])) {
+				rpc = "_" + rpc
+			}
+			name := rpc
+			for i := 2; used[name]; i++ {
+				name = fmt.Sprintf("%s%d", rpc, i)
+			}
+			if name != rpc {
+				g.warn("%s %s: rpc name %s is already taken, using %s", method, path, rpc, name)
+			}
+			used[name] = true
+			names[op] = name
+		}
+	}
+	return names
+}
+
+// requestType resolves the rpc input: the body schema of media type mt,
+// merged with the parameters when the body is an inline object, or a
+// message of just the parameters when there is no body schema. Any other
+// body next to parameters becomes a named field of the request. Form and
+// multipart bodies map their parts like any other object properties. It
+// returns the message name, the field the HTTP body binds to and the
+// message schema, nil when there is none.
+func (g *generator) requestType(rpc, mt string, body *openapi3.SchemaRef, params openapi3.Parameters) (string, string, *openapi3.Schema) {
+	paramSchema := g.paramsSchema(params)
+	if body != nil {
+		if matchMediaType("multipart/*", mt) && body.Ref == "" && body.Value != nil {
+			body = &openapi3.SchemaRef{Value: g.fileParts(body.Value)}
+		}
+		// an inline body shares the request message with path parameters;
+		// with "*" any other parameter would be read from the body, so then
+		// the body gets a field of its own
+		if body.Ref == "" && body.Value != nil && isMessage(body.Value) && paramSchema != nil && onlyPathParams(params) {
+			merged := &openapi3.Schema{AllOf: openapi3.SchemaRefs{{Value: paramSchema}, body}}
+			return g.addMessage(rpc+"Request", merged), "*", merged
+		}
+		if paramSchema != nil {
+			name := bodyField(body, paramSchema)
+			return g.addMessage(rpc+"Request", paramSchema), fieldName(name), paramSchema
+		}
+		t := g.resolveType(rpc, "Request", body)
+		if body.Value != nil && isMessage(body.Value) {
+			return t, "*", body.Value
+		}
+		return t, "*", nil
+	}
+	if paramSchema != nil {
+		return g.addMessage(rpc+"Request", paramSchema), "*", paramSchema
+	}
+	return "google.protobuf.Empty", "*", nil
+}
+
+// onlyPathParams reports whether every defined parameter is in the path
+func onlyPathParams(params openapi3.Parameters) bool {
+	for _, ref := range params {
+		if ref.Value != nil && ref.Value.In != openapi3.ParameterInPath {
+			return false
+		}
+	}
+	return true
+}
+
+// bodyField adds body to the request schema as a required field named
+// after the referenced schema (user for #/components/schemas/User), or
+// body for inline bodies, and returns the property name
+func bodyField(body *openapi3.SchemaRef, schema *openapi3.Schema) string {
+	name := "body"
+	if body.Ref != "" {
+		name = snakeCase(refName(body.Ref))
+	}
+	// keep clear of a parameter with the same name
+	if _, ok := schema.Properties[name]; ok {
+		name += "_body"
+	}
+	schema.Properties[name] = body
+	schema.Required = append(schema.Required, name)
+	return name
+}
+
+// requiredFields lists the proto names of the required fields of a
+// request message in declaration order, for google.api.method_signature
+func requiredFields(s *openapi3.Schema) []string {
+	props, required := flattenSchema(s)
+	var fields []string
+	for _, name := range slices.Sorted(maps.Keys(props)) {
+		if required[name] {
+			fields = append(fields, fieldName(name))
+		}
+	}
+	return fields
+}
+
+// defaultSuccessCodes is the order success responses are considered in
+// when Options.SuccessCodes is empty
+var defaultSuccessCodes = []string{"200", "201", "2xx", "default"}
+
+// successResponse picks the response an rpc returns, or "" when the
+// operation declares no success response. A range such as "2xx" matches
+// any code of that class, taken in sorted order.
+func (g *generator) successResponse(op *openapi3.Operation) (string, *openapi3.Response) {
+	responses := op.Responses.Map()
+	codes := slices.Sorted(maps.Keys(responses))
+	prefs := g.opts.SuccessCodes
+	if len(prefs) == 0 {
+		prefs = defaultSuccessCodes
+	}
+	for _, want := range prefs {
+		for _, code := range codes {
+			match := strings.EqualFold(code, want)
+			if class, ok := strings.CutSuffix(strings.ToLower(want), "xx"); ok && len(class) == 1 {
+				match = strings.HasPrefix(code, class)
+			}
+			if resp := response(g.doc, responses[code]); match && resp != nil {
+				return code, resp
+			}
+		}
+	}
+	return "", nil
+}
+
+// responseType resolves the rpc output from the chosen success response;
+// inline objects become <rpc>Response and arrays a list wrapper, while
+// 204 and responses without a schema are Empty. Under
+// Options.StreamArrays a 2xx array is instead streamed item by item,
+// reported by the second result. The third names the field holding the
+// HTTP response body, "items" for a list wrapper, or "" for the whole
+// message.
+func (g *generator) responseType(rpc string, code string, resp *openapi3.Response) (string, bool, string) {
+	if resp == nil || code == "204" {
+		return "google.protobuf.Empty", false, ""
+	}
+	mt, schema := g.mediaSchema(resp.Content)
+	if g.opts.ReadWriteSplit {
+		schema = g.directional(schema, "Output")
+	}
+	// plain text has no message shape of its own
+	if matchMediaType("text/plain", mt) {
+		return "google.protobuf.StringValue", false, ""
+	}
+	// raw downloads get a message carrying the bytes
+	if matchMediaType("application/octet-stream", mt) || schema != nil && schema.Ref == "" && isBinary(schema.Value) {
+		data := openapi3.NewObjectSchema()
+		data.Properties["data"] = &openapi3.SchemaRef{Value: openapi3.NewBytesSchema()}
+		data.Required = []string{"data"}
+		return g.addMessage(rpc+"Response", data), false, ""
+	}
+	if schema == nil {
+		return "google.protobuf.Empty", false, ""
+	}
+	// a component with no message of its own, such as an array, is
+	// answered as the schema it stands for
+	if schema.Ref != "" && !definesType(schema.Value) {
+		if _, ok := g.protoTypeOverride(schema.Value); !ok {
+			schema = &openapi3.SchemaRef{Value: schema.Value}
+		}
+	}
+	if s := schema.Value; schema.Ref == "" && s != nil && schemaType(s) == "array" && s.Items != nil {
+		if _, ok := g.protoTypeOverride(s); !ok {
+			if g.opts.StreamArrays && strings.HasPrefix(code, "2") {
+				item := g.arrayItem(rpc+"Response", s)
+				if w, ok := scalarWrappers[item]; ok {
+					item = w
+				}
+				// map and nested list items have no single message to stream
+				if !strings.ContainsAny(item, " <") {
+					return item, true, ""
+				}
+			}
+			// the list wrapper is not part of the REST response shape
+			return g.resolveType(rpc, "Response", schema), false, "items"
+		}
+	}
+	return g.resolveType(rpc, "Response", schema), false, ""
+}
+
+// isBinary reports whether s is a string of raw bytes
+func isBinary(s *openapi3.Schema) bool {
+	return s != nil && schemaType(s) == "string" && (s.Format == "binary" || s.Format == "byte")
+}
+
+// patchRequestType builds the request for a PATCH under Options.FieldMask:
+// the parameters, the body as a named field and an update_mask. It returns
+// the message name, the field the HTTP body binds to and the message schema.
+func (g *generator) patchRequestType(rpc string, body *openapi3.SchemaRef, params openapi3.Parameters) (string, string, *openapi3.Schema) {
+	schema := g.paramsSchema(params)
+	if schema == nil {
+		schema = openapi3.NewObjectSchema()
+	}
+	field := "*"
+	if body != nil {
+		field = fieldName(bodyField(body, schema))
+	}
+	schema.Properties["update_mask"] = &openapi3.SchemaRef{Value: &openapi3.Schema{
+		Extensions: map[string]any{"x-proto-type": "google.protobuf.FieldMask"},
+	}}
+	schema.Required = append(schema.Required, "update_mask")
+	return g.addMessage(rpc+"Request", schema), field, schema
+}
+
+// fileParts returns a copy of a multipart body schema whose binary parts
+// carry a note that large uploads may be better served by streaming
+func (g *generator) fileParts(body *openapi3.Schema) *openapi3.Schema {
+	copied := *body
+	copied.Properties = make(openapi3.Schemas, len(body.Properties))
+	for name, ref := range body.Properties {
+		part := ref.Value
+		if part != nil && schemaType(part) == "array" && part.Items != nil {
+			part = part.Items.Value
+		}
+		if isBinary(part) {
+			ref = &openapi3.SchemaRef{Ref: ref.Ref, Value: ref.Value}
+			g.comments[ref] = append(g.comments[ref], "file part; a client-streaming rpc may suit large uploads")
+		}
+		copied.Properties[name] = ref
+	}
+	return &copied
+}
+
+// defaultContentTypes is the media type preference used when
+// Options.ContentTypes is empty
+var defaultContentTypes = []string{"application/json", "application/*"}
+
+// mediaSchema picks the media type and schema to generate from, trying
+// the preferred content types in order ("type/*" matches any subtype) and
+// then the remaining types alphabetically. Media types with a schema win;
+// failing that the preferred type is returned with a nil schema.
+func (g *generator) mediaSchema(content openapi3.Content) (string, *openapi3.SchemaRef) {
+	prefs := g.opts.ContentTypes
+	if len(prefs) == 0 {
+		prefs = defaultContentTypes
+	}
+	prefs = slices.Concat(prefs, []string{"*/*"})
+	types := slices.Sorted(maps.Keys(content))
+	for _, pref := range prefs {
+		for _, mt := range types {
+			if media := content[mt]; media != nil && media.Schema != nil && matchMediaType(pref, mt) {
+				return mt, media.Schema
+			}
+		}
+	}
+	for _, pref := range prefs {
+		for _, mt := range types {
+			if matchMediaType(pref, mt) {
+				return mt, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// matchMediaType reports whether media type mt matches pattern, which may
+// end in a /* wildcard; parameters such as charset are ignored
+func matchMediaType(pattern, mt string) bool {
+	mt, _, _ = strings.Cut(strings.ToLower(mt), ";")
+	mt = strings.TrimSpace(mt)
+	pattern = strings.ToLower(pattern)
+	if pattern == "*/*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(mt, prefix)
+	}
+	return mt == pattern
+}
+
+// requestBody returns the body behind ref, looking it up in
+// components/requestBodies when the loader left the reference unresolved
+func requestBody(doc *openapi3.T, ref *openapi3.RequestBodyRef) *openapi3.RequestBody {
+	if ref == nil {
+		return nil
+	}
+	if ref.Value == nil && ref.Ref != "" && doc.Components != nil {
+		if c := doc.Components.RequestBodies[refName(ref.Ref)]; c != nil {
+			return c.Value
+		}
+	}
+	return ref.Value
+}
+
+// response returns the response behind ref, looking it up in
+// components/responses when the loader left the reference unresolved
+func response(doc *openapi3.T, ref *openapi3.ResponseRef) *openapi3.Response {
+	if ref == nil {
+		return nil
+	}
+	if ref.Value == nil && ref.Ref != "" && doc.Components != nil {
+		if c := doc.Components.Responses[refName(ref.Ref)]; c != nil {
+			return c.Value
+		}
+	}
+	return ref.Value
+}
+
+// writeRPCComment documents an rpc with the operation summary, then its
+// description, then the HTTP method and path it was generated from and
+// the response code its output type was taken from. Bodies sent as
+// anything but JSON note their media type, since transcoding assumes JSON.
+func writeRPCComment(svc *strings.Builder, indent, path, method string, op *openapi3.Operation, code, bodyMedia string) {
+	for _, text := range []string{op.Summary, op.Description} {
+		if strings.TrimSpace(text) != "" {
+			writeComment(svc, indent, text)
+			svc.WriteString(indent + "//\n")
+		}
+	}
+	line := strings.ToUpper(method) + " " + path
+	if code != "" {
+		line += " -> " + code
+	}
+	writeComment(svc, indent, line)
+	if bodyMedia != "" && !isJSON(bodyMedia) {
+		writeComment(svc, indent, "body: "+bodyMedia)
+	}
+}
+
+// isJSON reports whether mt is application/json or a +json media type
+func isJSON(mt string) bool {
+	mt, _, _ = strings.Cut(strings.ToLower(mt), ";")
+	mt = strings.TrimSpace(mt)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// writeErrorComments documents the 4xx/5xx responses of an operation
+// above its rpc, pointing at google.rpc.Status as the gRPC error type
+func (g *generator) writeErrorComments(svc *strings.Builder, op *openapi3.Operation) {
+	responses := op.Responses.Map()
+	var codes []string
+	for _, code := range slices.Sorted(maps.Keys(responses)) {
+		if strings.HasPrefix(code, "4") || strings.HasPrefix(code, "5") {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return
+	}
+	g.imports["google/rpc/status.proto"] = true
+	svc.WriteString(g.indent + "//\n" + g.indent + "// Errors are returned as google.rpc.Status:\n")
+	for _, code := range codes {
+		line := g.indent + "//   " + code
+		if r := response(g.doc, responses[code]); r != nil && r.Description != nil && *r.Description != "" {
+			line += ": " + strings.Join(strings.Fields(*r.Description), " ")
+		}
+		svc.WriteString(line + "\n")
+	}
+}
+
+// mergeParams combines path-level and operation-level parameters, the
+// operation winning when both declare the same name and location.
+// References the loader left unresolved are looked up in
+// components/parameters.
+func mergeParams(doc *openapi3.T, pathParams, opParams openapi3.Parameters) openapi3.Parameters {
+	opParams = resolveParams(doc, opParams)
+	var merged openapi3.Parameters
+	for _, ref := range resolveParams(doc, pathParams) {
+		if p := ref.Value; p != nil && opParams.GetByInAndName(p.In, p.Name) != nil {
+			continue
+		}
+		merged = append(merged, ref)
+	}
+	return append(merged, opParams...)
+}
+
+// resolveParams fills in the value of parameter refs that only carry a
+// $ref, leaving those that name no component with a nil value
+func resolveParams(doc *openapi3.T, params openapi3.Parameters) openapi3.Parameters {
+	resolved := make(openapi3.Parameters, 0, len(params))
+	for _, ref := range params {
+		if ref != nil && ref.Value == nil && ref.Ref != "" && doc.Components != nil {
+			if c := doc.Components.Parameters[refName(ref.Ref)]; c != nil {
+				ref = &openapi3.ParameterRef{Ref: ref.Ref, Value: c.Value}
+			}
+		}
+		if ref != nil {
+			resolved = append(resolved, ref)
+		}
+	}
+	return resolved
+}
+
+// standardHeaders are transport-level headers left out of request
+// messages unless Options.IncludeStandardHeaders is set
+var standardHeaders = map[string]bool{
+	"authorization":  true,
+	"content-type":   true,
+	"content-length": true,
+	"accept":         true,
+	"user-agent":     true,
+}
+
+// paramsSchema builds an object schema with one property per path, query,
+// header and cookie parameter, or nil when the operation has none
+func (g *generator) paramsSchema(params openapi3.Parameters) *openapi3.Schema {
+	schema := openapi3.NewObjectSchema()
+	// location of each parameter name, since the fields are keyed by name
+	in := make(map[string]string)
+	for _, ref := range params {
+		p := ref.Value
+		if p == nil {
+			g.warn("parameter %s has no definition, skipping it", ref.Ref)
+			continue
+		}
+		if p.Schema == nil {
+			continue
+		}
+		fld := p.Schema
+		switch p.In {
+		case openapi3.ParameterInPath, openapi3.ParameterInQuery:
+		case openapi3.ParameterInHeader:
+			if standardHeaders[strings.ToLower(p.Name)] && !g.opts.IncludeStandardHeaders {
+				continue
+			}
+		case openapi3.ParameterInCookie:
+			// cookies travel outside the path and query the HTTP rule binds
+			fld = &openapi3.SchemaRef{Ref: p.Schema.Ref, Value: p.Schema.Value}
+			g.comments[fld] = append(g.comments[fld], fmt.Sprintf("from the %s cookie", p.Name))
+		default:
+			continue
+		}
+		if fld.Value != nil && schemaType(fld.Value) == "array" {
+			// repeated fields carry no wire hint for how the list was encoded
+			if sm, err := p.SerializationMethod(); err == nil {
+				comments := g.comments[fld]
+				fld = &openapi3.SchemaRef{Ref: p.Schema.Ref, Value: p.Schema.Value}
+				g.comments[fld] = append(comments, fmt.Sprintf("style: %s, explode: %t", sm.Style, sm.Explode))
+			}
+		}
+		if prev, ok := in[p.Name]; ok {
+			g.fail(fmt.Errorf("%s: %s and %s parameters named %q both map to field %s", g.operation, prev, p.In, p.Name, fieldName(p.Name)))
+			continue
+		}
+		in[p.Name] = p.In
+		schema.Properties[p.Name] = fld
+		if p.Required {
+			schema.Required = append(schema.Required, p.Name)
+		}
+	}
+	if len(schema.Properties) == 0 {
+		return nil
+	}
+	return schema
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// bindPath rewrites path template variables to the proto field names they
+// bind to, e.g. /users/{userId} -> /users/{user_id}
+func bindPath(path string) string {
+	return pathParamPattern.ReplaceAllStringFunc(path, func(m string) string {
+		return "{" + fieldName(m[1:len(m)-1]) + "}"
+	})
+}
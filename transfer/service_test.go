@@ -0,0 +1,867 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParameterRequestMessages(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+        - {name: active, in: query, schema: {type: boolean}}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message GetUserRequest {\n  optional bool active = 1;\n  string id = 2;\n}",
+		"(GetUserRequest) returns (google.protobuf.Empty)",
+	)
+}
+
+func TestParameterNameInTwoLocations(t *testing.T) {
+	_, err := Generate(loadSpec(t, specHeader+`paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+        - {name: id, in: query, schema: {type: string}}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`), DefaultOptions())
+	want := `GET /users/{id}: path and query parameters named "id" both map to field id`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Generate error = %v, want %q", err, want)
+	}
+}
+
+func TestPathLevelParameters(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /tenants/{tenantId}/users:
+    parameters:
+      - {name: tenantId, in: path, required: true, schema: {type: string}}
+    get:
+      operationId: listUsers
+      responses:
+        "204": {description: ok}
+    delete:
+      operationId: deleteUsers
+      parameters:
+        - {name: force, in: query, schema: {type: boolean}}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message ListUsersRequest {\n  string tenant_id = 1 [json_name = \"tenantId\"];\n}",
+		"message DeleteUsersRequest {\n  optional bool force = 1;\n  string tenant_id = 2 [json_name = \"tenantId\"];\n}",
+	)
+}
+
+func TestHeaderParameters(t *testing.T) {
+	spec := specHeader + `paths:
+  /users:
+    get:
+      operationId: listUsers
+      parameters:
+        - {name: X-Request-Id, in: header, schema: {type: string}}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`
+	out := generate(t, spec, DefaultOptions())
+	assertContains(t, out, "message ListUsersRequest {\n  optional string x_request_id = 1 [json_name = \"X-Request-Id\"];\n}")
+}
+
+func TestArrayQueryParameter(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      parameters:
+        - {name: tags, in: query, schema: {type: array, items: {type: string}}}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out, "  // style: form, explode: true\n  repeated string tags = 1;")
+}
+
+func TestInlineRequestBody(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users/{id}:
+    post:
+      operationId: updateUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name: {type: string}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message UpdateUserRequest {\n  string id = 1;\n  optional string name = 2;\n}",
+		"(UpdateUserRequest) returns (google.protobuf.Empty)",
+		"body: \"*\"",
+	)
+}
+
+func TestInlineResponses(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /status:
+    get:
+      operationId: getStatus
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  ok: {type: boolean}
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/User'}}
+components:
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message GetStatusResponse {\n  optional bool ok = 1;\n}",
+		"(google.protobuf.Empty) returns (GetStatusResponse)",
+		"message ListUsersResponse {\n  repeated User items = 1;\n}",
+		"(google.protobuf.Empty) returns (ListUsersResponse)",
+	)
+}
+
+func TestListResponseWrapperIsShared(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/User'}}
+  /admins:
+    get:
+      operationId: listAdmins
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/User'}}
+components:
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+`, DefaultOptions())
+	if n := strings.Count(out, "message ListUsersResponse {"); n != 1 {
+		t.Errorf("ListUsersResponse is defined %d times:\n%s", n, out)
+	}
+	assertContains(t, out, "(google.protobuf.Empty) returns (ListUsersResponse)")
+}
+
+func TestSynthesizedNameTakenBySchema(t *testing.T) {
+	tests := []struct {
+		name, paths, schema, want string
+	}{
+		{"request", `
+  /users/{id}:
+    get:
+      operationId: getUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "204": {description: ok}`, "GetUserRequest", `GET /users/{id}: schema "GetUserRequest"`},
+		{"response", `
+  /users:
+    post:
+      operationId: createUser
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: object, properties: {id: {type: string}}}`, "CreateUserResponse", `POST /users: schema "CreateUserResponse"`},
+		{"list wrapper", `
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/User'}}`, "ListUsersResponse", `GET /users: schema "ListUsersResponse"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := specHeader + "paths:" + tt.paths + `
+components:
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+    ` + tt.schema + `: {type: object, properties: {note: {type: string}}}
+`
+			_, err := Generate(loadSpec(t, spec), DefaultOptions())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Generate error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorComments(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ErrorComments = true
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "204": {description: ok}
+        "400": {description: bad request}
+        "404": {description: not found}
+        "500": {description: server error}
+components: {schemas: {}}
+`, opts)
+	assertContains(t, out,
+		"import \"google/rpc/status.proto\";",
+		"  // Errors are returned as google.rpc.Status:\n  //   400: bad request\n  //   404: not found\n  //   500: server error\n  rpc ",
+	)
+}
+
+func TestRPCComments(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      summary: List users
+      description: Returns every registered user.
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out, "  // List users\n  //\n  // Returns every registered user.\n")
+}
+
+func TestNoHTTPAnnotations(t *testing.T) {
+	opts := DefaultOptions()
+	opts.EmitHTTPAnnotations = false
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, opts)
+	assertContains(t, out, "(google.protobuf.Empty) returns (google.protobuf.Empty);")
+	if strings.Contains(out, "google.api.http") || strings.Contains(out, "google/api/annotations.proto") {
+		t.Errorf("output has HTTP annotations:\n%s", out)
+	}
+}
+
+func TestGroupByTag(t *testing.T) {
+	opts := DefaultOptions()
+	opts.GroupByTag = true
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      tags: [users]
+      responses:
+        "204": {description: ok}
+  /orders:
+    get:
+      operationId: listOrders
+      tags: [orders]
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, opts)
+	assertContains(t, out,
+		"service OrdersService {\n  // GET /orders",
+		"service UsersService {\n  // GET /users",
+	)
+	if strings.Contains(out, "ApiService") {
+		t.Errorf("tagged operations left in ApiService:\n%s", out)
+	}
+}
+
+func TestDeprecatedOperationsAndFields(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users:
+    post:
+      operationId: createUser
+      deprecated: true
+      responses:
+        "204": {description: ok}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        legacy: {type: string, deprecated: true}
+`, DefaultOptions())
+	assertContains(t, out,
+		"  // Deprecated.\n  optional string legacy = 1 [deprecated = true];",
+		"  // Deprecated.\n  rpc ",
+		"    option deprecated = true;\n",
+	)
+}
+
+func TestSkipDeprecated(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SkipDeprecated = true
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "204": {description: ok}
+    post:
+      operationId: createUser
+      deprecated: true
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, opts)
+	assertContains(t, out, `get: "/users"`)
+	if strings.Contains(out, `post: "/users"`) {
+		t.Errorf("deprecated operation was kept:\n%s", out)
+	}
+}
+
+func TestSkipDeprecatedEnumIsNotReused(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SkipDeprecated = true
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Status: {type: string, enum: [active, inactive], deprecated: true}
+    Job:
+      type: object
+      properties:
+        state: {type: string, enum: [active, inactive]}
+`, opts)
+	// the inline enum gets its own definition instead of naming the
+	// skipped Status
+	assertContains(t, out, "  enum StateEnum {\n", "optional StateEnum state = 1;")
+	if strings.Contains(out, "Status") {
+		t.Errorf("output references the skipped Status enum:\n%s", out)
+	}
+}
+
+func TestComponentResponses(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200": {$ref: '#/components/responses/PetResponse'}
+  /pets/{id}:
+    get:
+      operationId: getPet
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "200": {$ref: '#/components/responses/PetResponse'}
+components:
+  responses:
+    PetResponse:
+      description: ok
+      content:
+        application/json:
+          schema: {$ref: '#/components/schemas/Pet'}
+  schemas:
+    Pet: {type: object, properties: {name: {type: string}}}
+`, DefaultOptions())
+	if n := strings.Count(out, ") returns (Pet)"); n != 2 {
+		t.Errorf("%d rpcs return Pet, want 2:\n%s", n, out)
+	}
+}
+
+func TestPreferJSONContent(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /pets:
+    get:
+      operationId: getPet
+      responses:
+        "200":
+          description: ok
+          content:
+            application/xml:
+              schema: {type: object, properties: {x: {type: string}}}
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:
+    Pet: {type: object, properties: {name: {type: string}}}
+`, DefaultOptions())
+	assertContains(t, out, "(google.protobuf.Empty) returns (Pet)")
+}
+
+func TestNoContentResponses(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /pets:
+    get:
+      operationId: getPet
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+        "default": {description: error}
+    delete:
+      operationId: deletePets
+      responses:
+        "204": {description: gone}
+components:
+  schemas:
+    Pet: {type: object, properties: {name: {type: string}}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"  // DELETE /pets -> 204\n  rpc ", "(google.protobuf.Empty) returns (google.protobuf.Empty)",
+		"  // GET /pets -> 200\n  rpc ", "(google.protobuf.Empty) returns (Pet)",
+	)
+}
+
+func TestSuccessCodePreference(t *testing.T) {
+	spec := specHeader + `paths:
+  /items:
+    post:
+      operationId: createItem
+      responses:
+        "200":
+          description: ok
+          content: {application/json: {schema: {$ref: '#/components/schemas/A'}}}
+        "201":
+          description: created
+          content: {application/json: {schema: {$ref: '#/components/schemas/B'}}}
+components:
+  schemas:
+    A: {type: object, properties: {a: {type: string}}}
+    B: {type: object, properties: {b: {type: string}}}
+`
+	out := generate(t, spec, DefaultOptions())
+	assertContains(t, out, "  // POST /items -> 200\n", "(google.protobuf.Empty) returns (A)")
+	opts := DefaultOptions()
+	opts.SuccessCodes = []string{"201"}
+	out = generate(t, spec, opts)
+	assertContains(t, out, "  // POST /items -> 201\n", "(google.protobuf.Empty) returns (B)")
+}
+
+func TestMultipartBody(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /upload:
+    post:
+      operationId: upload
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              properties:
+                file: {type: string, format: binary}
+                title: {type: string}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message UploadRequest {\n  // file part; a client-streaming rpc may suit large uploads\n  optional bytes file = 1;\n  optional string title = 2;\n}",
+		"(UploadRequest) returns (google.protobuf.Empty)",
+	)
+}
+
+func TestFormEncodedBody(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /login:
+    post:
+      operationId: login
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            schema:
+              type: object
+              properties:
+                username: {type: string}
+                password: {type: string}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message LoginRequest {\n  optional string password = 1;\n  optional string username = 2;\n}",
+		"  // body: application/x-www-form-urlencoded\n",
+	)
+}
+
+func TestPatchFieldMask(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FieldMask = true
+	out := generate(t, specHeader+`paths:
+  /items/{id}:
+    patch:
+      operationId: updateItem
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      requestBody:
+        content: {application/json: {schema: {$ref: '#/components/schemas/Item'}}}
+      responses:
+        "200":
+          description: ok
+          content: {application/json: {schema: {$ref: '#/components/schemas/Item'}}}
+components:
+  schemas:
+    Item: {type: object, properties: {name: {type: string}}}
+`, opts)
+	assertContains(t, out,
+		`import "google/protobuf/field_mask.proto";`,
+		"message UpdateItemRequest {\n  string id = 1;\n  Item item = 2;\n  google.protobuf.FieldMask update_mask = 3;\n}",
+		`body: "item"`,
+	)
+}
+
+func TestTextPlainResponse(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /text:
+    get:
+      operationId: getText
+      responses:
+        "200":
+          description: ok
+          content: {text/plain: {schema: {type: string}}}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out,
+		`import "google/protobuf/wrappers.proto";`,
+		"(google.protobuf.Empty) returns (google.protobuf.StringValue)",
+	)
+}
+
+func TestOctetStreamResponse(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /download:
+    get:
+      operationId: download
+      responses:
+        "200":
+          description: ok
+          content: {application/octet-stream: {schema: {type: string, format: binary}}}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message DownloadResponse {\n  bytes data = 1;\n}",
+		"(google.protobuf.Empty) returns (DownloadResponse)",
+	)
+}
+
+func TestOperationIDRPCNames(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users/{id}:
+    get:
+      operationId: get-user
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "204": {description: ok}
+    put:
+      operationId: get_user
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"rpc GetUser(GetUserRequest) returns (google.protobuf.Empty)",
+		"rpc GetUser2(GetUser2Request) returns (google.protobuf.Empty)",
+	)
+}
+
+func TestCollidingPathRPCNames(t *testing.T) {
+	var warnings []string
+	opts := DefaultOptions()
+	opts.Warn = func(msg string) { warnings = append(warnings, msg) }
+	out := generate(t, specHeader+`paths:
+  /user-groups:
+    get:
+      responses:
+        "204": {description: ok}
+  /user_groups:
+    get:
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, opts)
+	assertContains(t, out,
+		"rpc GetUserGroups(google.protobuf.Empty)",
+		"rpc GetUserGroups2(google.protobuf.Empty)",
+	)
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %q, want one for the renamed rpc", warnings)
+	}
+}
+
+func TestParameterRefs(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      parameters:
+        - {$ref: '#/components/parameters/Limit'}
+      responses:
+        "204": {description: ok}
+  /orders:
+    get:
+      operationId: listOrders
+      parameters:
+        - {$ref: '#/components/parameters/Limit'}
+      responses:
+        "204": {description: ok}
+components:
+  parameters:
+    Limit: {name: limit, in: query, schema: {type: integer}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message ListOrdersRequest {\n  optional int32 limit = 1;\n}",
+		"message ListUsersRequest {\n  optional int32 limit = 1;\n}",
+	)
+}
+
+func TestCookieParameters(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /orders:
+    get:
+      operationId: listOrders
+      parameters:
+        - {name: session, in: cookie, schema: {type: string}}
+      responses:
+        "204": {description: ok}
+`, DefaultOptions())
+	assertContains(t, out, "message ListOrdersRequest {\n  // from the session cookie\n  optional string session = 1;\n}")
+}
+
+func TestStreamingExtension(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /events:
+    get:
+      operationId: watchEvents
+      x-streaming: server
+      responses:
+        "200":
+          description: ok
+          content: {application/json: {schema: {$ref: '#/components/schemas/Event'}}}
+    post:
+      operationId: uploadEvents
+      x-streaming: client
+      requestBody:
+        content: {application/json: {schema: {$ref: '#/components/schemas/Event'}}}
+      responses:
+        "204": {description: ok}
+    put:
+      operationId: chat
+      x-streaming: bidi
+      requestBody:
+        content: {application/json: {schema: {$ref: '#/components/schemas/Event'}}}
+      responses:
+        "200":
+          description: ok
+          content: {application/json: {schema: {$ref: '#/components/schemas/Event'}}}
+components:
+  schemas:
+    Event: {type: object, properties: {id: {type: string}}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"rpc WatchEvents(google.protobuf.Empty) returns (stream Event)",
+		"rpc UploadEvents(stream Event) returns (google.protobuf.Empty)",
+		"rpc Chat(stream Event) returns (stream Event)",
+	)
+}
+
+func TestStreamArrays(t *testing.T) {
+	opts := DefaultOptions()
+	opts.StreamArrays = true
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200":
+          description: ok
+          content: {application/json: {schema: {type: array, items: {$ref: '#/components/schemas/User'}}}}
+components:
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+`, opts)
+	assertContains(t, out, "rpc ListUsers(google.protobuf.Empty) returns (stream User)")
+	if strings.Contains(out, "ListUsersResponse") {
+		t.Errorf("streamed array response still has a wrapper:\n%s", out)
+	}
+}
+
+func TestMethodSignature(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MethodSignature = true
+	out := generate(t, specHeader+`paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+        - {name: view, in: query, required: true, schema: {type: string}}
+        - {name: verbose, in: query, schema: {type: boolean}}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, opts)
+	assertContains(t, out,
+		`import "google/api/client.proto";`,
+		"  rpc GetUser(GetUserRequest) returns (google.protobuf.Empty) {\n    option (google.api.method_signature) = \"id,view\";\n",
+	)
+}
+
+func TestAdditionalBindings(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users/{id}:
+    post:
+      operationId: updateUser
+      x-additional-bindings:
+        - {method: put, path: "/v2/users/{id}"}
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      requestBody:
+        content: {application/json: {schema: {type: object, properties: {name: {type: string}}}}}
+      responses:
+        "204": {description: ok}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out, "      post: \"/users/{id}\"\n      body: \"*\"\n      additional_bindings {\n        put: \"/v2/users/{id}\"\n        body: \"*\"\n      }\n    };")
+}
+
+func TestNamedBodyField(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users/{id}:
+    post:
+      operationId: updateUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      requestBody:
+        content: {application/json: {schema: {$ref: '#/components/schemas/User'}}}
+      responses:
+        "204": {description: ok}
+components:
+  schemas:
+    User: {type: object, properties: {name: {type: string}}}
+`, DefaultOptions())
+	assertContains(t, out,
+		"message UpdateUserRequest {\n  string id = 1;\n  User user = 2;\n}",
+		"      post: \"/users/{id}\"\n      body: \"user\"\n",
+	)
+}
+
+func TestResponseBodyForListWrapper(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200":
+          description: ok
+          content: {application/json: {schema: {type: array, items: {$ref: '#/components/schemas/User'}}}}
+components:
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+`, DefaultOptions())
+	assertContains(t, out, "      get: \"/users\"\n      response_body: \"items\"\n")
+}
+
+func TestInlineBodyWithParams(t *testing.T) {
+	body := `      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name: {type: string}
+      responses:
+        "204": {description: ok}
+`
+	spec := specHeader + `paths:
+  /users/{id}:
+    post:
+      operationId: updateUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+        - {name: dryRun, in: query, schema: {type: boolean}}
+` + body + `    put:
+      operationId: replaceUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+` + body
+	out := generate(t, spec, Options{EmitHTTPAnnotations: true})
+	// query parameters stay bindable, so the body gets its own field
+	assertContains(t, out,
+		"message UpdateUserRequest {\n  message Body {\n    optional string name = 1;\n  }\n  Body body = 1;\n  optional bool dry_run = 2",
+		"post: \"/users/{id}\"\n      body: \"body\"",
+	)
+	// with only path parameters the body is merged in
+	assertContains(t, out,
+		"message ReplaceUserRequest {\n  string id = 1;\n  optional string name = 2;\n}",
+		"put: \"/users/{id}\"\n      body: \"*\"",
+	)
+}
+
+func TestReadWriteSplitBodyField(t *testing.T) {
+	spec := specHeader + `paths:
+  /users/{id}:
+    put:
+      operationId: updateUser
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/User'}
+      responses:
+        "204": {description: ok}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: string, readOnly: true}
+        name: {type: string}
+`
+	out := generate(t, spec, Options{EmitHTTPAnnotations: true, ReadWriteSplit: true, PGV: true, PGVRequired: true})
+	assertContains(t, out,
+		"message UserInput {\n  reserved 1;\n  optional string name = 2;\n}",
+		"UserInput user = 2 [(validate.rules).message.required = true];",
+		`body: "user"`,
+	)
+}
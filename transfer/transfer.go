@@ -0,0 +1,397 @@
+// Package transfer converts OpenAPI documents into proto3 definitions.
+package transfer
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Options configures generation
+type Options struct {
+	// PackageName is the proto package; empty derives it from the
+	// document title, falling back to "generated"
+	PackageName string
+	// GoPackage, when set, is emitted as option go_package
+	GoPackage string
+	// EmitHTTPAnnotations adds google.api.http options to each rpc
+	EmitHTTPAnnotations bool
+	// Int64AsString maps int64 integers to string, avoiding precision
+	// loss in JavaScript clients
+	Int64AsString bool
+	// UnsignedFromMinimum maps integers with minimum >= 0 to uint32/uint64
+	UnsignedFromMinimum bool
+	// IncludeStandardHeaders keeps header parameters such as Authorization
+	// and Content-Type in request messages
+	IncludeStandardHeaders bool
+	// GroupByTag emits one service per operation tag instead of a single
+	// ApiService; untagged operations stay in ApiService
+	GroupByTag bool
+	// ErrorComments documents 4xx/5xx responses above each rpc,
+	// importing google/rpc/status.proto when any are found
+	ErrorComments bool
+	// SkipDeprecated leaves out deprecated operations, and deprecated
+	// schemas nothing else still references
+	SkipDeprecated bool
+	// PGV adds protoc-gen-validate rules derived from schema constraints
+	PGV bool
+	// Protovalidate emits the same rules as buf protovalidate
+	// (buf.validate.field) options instead of PGV ones
+	Protovalidate bool
+	// PGVRequired adds rules enforcing the schema's required set: message
+	// fields must be present and strings and bytes non-empty
+	PGVRequired bool
+	// TypeMap overrides the built-in mapping for "type/format" keys such
+	// as "string/uuid", consulted before the defaults
+	TypeMap map[string]string
+	// ContentTypes orders the media types preferred when a body or
+	// response offers several; entries may end in /*. Empty means
+	// application/json, then application/*, then anything.
+	ContentTypes []string
+	// SuccessCodes orders the response codes the rpc output is taken
+	// from; "2xx" matches any 2xx code. Empty means 200, 201, 2xx, default.
+	SuccessCodes []string
+	// FieldMask shapes PATCH requests as partial updates: the body becomes
+	// a named field bound by the HTTP rule, next to a
+	// google.protobuf.FieldMask update_mask
+	FieldMask bool
+	// Warn, when set, receives non-fatal problems such as properties with
+	// no proto equivalent, each naming the message and field
+	Warn func(msg string)
+	// Workers, when above 1, writes component schemas on that many
+	// goroutines; output is the same as with a single worker
+	Workers int
+	// Indent is one level of indentation; empty means two spaces
+	Indent string
+	// ReadWriteSplit leaves readOnly properties out of rpc requests and
+	// writeOnly properties out of responses
+	ReadWriteSplit bool
+	// StreamArrays turns 2xx array responses into server-streaming rpcs
+	// of the item type instead of returning a List...Response wrapper
+	StreamArrays bool
+	// MethodSignature adds a google.api.method_signature option listing
+	// the required fields of each rpc request
+	MethodSignature bool
+}
+
+// goPackagePattern roughly matches a Go import path with an optional
+// ";name" package override
+var goPackagePattern = regexp.MustCompile(`^[\w.~-]+(/[\w.~-]+)*(;\w+)?$`)
+
+// DefaultOptions returns the options used by the CLI
+func DefaultOptions() Options {
+	return Options{
+		EmitHTTPAnnotations: true,
+	}
+}
+
+// Generate builds .proto text from an OpenAPI document
+func Generate(doc *openapi3.T, opts Options) (string, error) {
+	if doc == nil {
+		return "", fmt.Errorf("%w: nil OpenAPI document", ErrGenerate)
+	}
+	doc = withComponents(doc)
+	if opts.GoPackage != "" && !goPackagePattern.MatchString(opts.GoPackage) {
+		return "", fmt.Errorf("%w: invalid go_package %q: expected an import path like example.com/api/v1[;name]", ErrGenerate, opts.GoPackage)
+	}
+	proto, err := generateProto(doc, opts)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrGenerate, err)
+	}
+	return proto, nil
+}
+
+// withComponents returns doc, or a shallow copy with an empty components
+// object when the spec has none, so a paths-only spec generates cleanly
+func withComponents(doc *openapi3.T) *openapi3.T {
+	if doc.Components != nil {
+		return doc
+	}
+	d := *doc
+	d.Components = &openapi3.Components{}
+	return &d
+}
+
+// generator holds state collected while building the proto body
+type generator struct {
+	opts    Options
+	doc     *openapi3.T
+	imports map[string]bool
+	// top-level type names, used to keep nested message names unambiguous
+	types map[string]bool
+	// top-level enums keyed by enumKey, reused by identical inline enums
+	enums map[string]string
+	// extra comment lines for synthesized fields, keyed by their schema ref
+	comments map[*openapi3.SchemaRef][]string
+	// messages synthesized for request/response bodies, mapped to the file
+	// that defines them
+	extra     strings.Builder
+	generated map[string]string
+	// synthFiles assigns synthesized types to files in split output, and
+	// common writes the ones that go to common.proto
+	synthFiles map[string]string
+	common     *generator
+	// file is the proto file being written when output is split, and
+	// schemaFiles the file each top-level type lives in
+	file        string
+	schemaFiles map[string]string
+	// schemas left out under SkipDeprecated
+	skipped map[string]bool
+	// err is the first problem found in the document, reported by Generate
+	err error
+	// scope names the message being written, for warnings
+	scope string
+	// operation names the METHOD path of the rpc being written, for errors
+	operation string
+	// warnings buffered by a fork until it is joined
+	warnings []string
+	// expanding maps the schemas writeMessage is inside of to their
+	// message names, so self-containing inline objects terminate
+	expanding map[*openapi3.Schema]string
+	// inlining holds the components mapType is mapping through their
+	// value, so components that contain themselves terminate
+	inlining map[*openapi3.Schema]bool
+	// indent is one level of indentation
+	indent string
+	// rpcs holds the unique rpc name of every operation
+	rpcs map[*openapi3.Operation]string
+}
+
+// newGenerator prepares a generator for doc
+func newGenerator(doc *openapi3.T, opts Options) *generator {
+	g := &generator{
+		opts:        opts,
+		doc:         doc,
+		imports:     make(map[string]bool),
+		types:       make(map[string]bool),
+		enums:       make(map[string]string),
+		comments:    make(map[*openapi3.SchemaRef][]string),
+		generated:   make(map[string]string),
+		schemaFiles: make(map[string]string),
+		expanding:   make(map[*openapi3.Schema]string),
+		inlining:    make(map[*openapi3.Schema]bool),
+		indent:      opts.Indent,
+	}
+	if g.indent == "" {
+		g.indent = "  "
+	}
+	if opts.SkipDeprecated {
+		g.skipped = deprecatedSchemas(doc)
+	}
+	owners := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(doc.Components.Schemas)) {
+		schemaRef := doc.Components.Schemas[name]
+		// sanitizing can fold distinct schema names into one identifier
+		if prev, ok := owners[typeName(name)]; ok {
+			g.fail(fmt.Errorf("schemas %q and %q both map to proto type %s", prev, name, typeName(name)))
+		}
+		owners[typeName(name)] = name
+		g.types[typeName(name)] = true
+		// a skipped enum is never written, so inline enums cannot reuse it
+		if len(enumValues(schemaRef.Value)) > 0 && !g.skipped[name] {
+			key := enumKey(schemaRef.Value)
+			// keep the first name in sorted order when several enums match
+			if prev, ok := g.enums[key]; !ok || typeName(name) < prev {
+				g.enums[key] = typeName(name)
+			}
+		}
+	}
+	g.rpcs = g.rpcNames(doc)
+	return g
+}
+
+// deprecatedSchemas lists the deprecated component schemas that no kept
+// operation or schema references
+func deprecatedSchemas(doc *openapi3.T) map[string]bool {
+	used := make(map[string]bool)
+	for _, pathItem := range doc.Paths.Map() {
+		for _, op := range pathItem.Operations() {
+			if op.Deprecated {
+				continue
+			}
+			for _, ref := range operationSchemas(doc, mergeParams(doc, pathItem.Parameters, op.Parameters), op) {
+				collectRefs(doc, ref, used)
+			}
+		}
+	}
+	for name, ref := range doc.Components.Schemas {
+		if ref.Value != nil && !ref.Value.Deprecated {
+			used[name] = true
+			collectRefs(doc, &openapi3.SchemaRef{Value: ref.Value}, used)
+		}
+	}
+	skipped := make(map[string]bool)
+	for name := range doc.Components.Schemas {
+		if !used[name] {
+			skipped[name] = true
+		}
+	}
+	return skipped
+}
+
+// fail records err unless an earlier error was already recorded
+func (g *generator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// warn reports a non-fatal problem through Options.Warn
+func (g *generator) warn(format string, args ...interface{}) {
+	if g.opts.Warn != nil {
+		g.opts.Warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// generateProto builds .proto text from OpenAPI document
+func generateProto(doc *openapi3.T, opts Options) (string, error) {
+	g := newGenerator(doc, opts)
+	// Sorted iteration keeps output and field numbering reproducible
+	body := g.writeSchemas(slices.Sorted(maps.Keys(doc.Components.Schemas)))
+	// Service, written separately so request/response messages generated
+	// while resolving bodies can be placed ahead of it
+	svc := g.writeService(doc)
+	// Header, written last so it only imports what generation used
+	if g.err != nil {
+		return "", g.err
+	}
+	return g.header() + body + g.extra.String() + svc, nil
+}
+
+// writeSchemas emits the enums and messages for the named component schemas
+func (g *generator) writeSchemas(names []string) string {
+	// each schema is written by its own fork so they can run in parallel;
+	// joining the forks in order keeps output identical to a serial run
+	parts := make([]string, len(names))
+	forks := make([]*generator, len(names))
+	work := func(i int) {
+		forks[i] = g.fork()
+		parts[i] = forks[i].writeSchema(names[i])
+	}
+	if g.opts.Workers > 1 {
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for range min(g.opts.Workers, len(names)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					work(i)
+				}
+			}()
+		}
+		for i := range names {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	} else {
+		for i := range names {
+			work(i)
+		}
+	}
+	for _, f := range forks {
+		g.join(f)
+	}
+	return strings.Join(parts, "")
+}
+
+// writeSchema emits the enum and/or message for one component schema
+func (g *generator) writeSchema(name string) string {
+	var b strings.Builder
+	if g.skipped[name] {
+		return ""
+	}
+	schema := g.doc.Components.Schemas[name].Value
+	// x-proto-type replaces the schema with an existing type
+	if _, ok := schema.Extensions["x-proto-type"].(string); ok {
+		return ""
+	}
+	// top-level enum
+	if len(enumValues(schema)) > 0 {
+		writeComment(&b, "", schema.Description)
+		g.writeEnum(&b, typeName(name), schema, "")
+		b.WriteString("\n")
+	}
+	// message for object schemas
+	if isMessage(schema) {
+		g.writeMessage(&b, typeName(name), schema, "")
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// fork returns a copy of g that writes schemas without touching g: it
+// has its own imports, error and buffered warnings. Everything else it
+// only reads.
+func (g *generator) fork() *generator {
+	f := *g
+	f.imports = make(map[string]bool)
+	f.extra = strings.Builder{}
+	f.expanding = make(map[*openapi3.Schema]string)
+	f.inlining = make(map[*openapi3.Schema]bool)
+	f.err = nil
+	f.warnings = nil
+	f.opts.Warn = func(msg string) { f.warnings = append(f.warnings, msg) }
+	return &f
+}
+
+// join folds what a fork collected back into g
+func (g *generator) join(f *generator) {
+	maps.Copy(g.imports, f.imports)
+	if f.err != nil {
+		g.fail(f.err)
+	}
+	for _, msg := range f.warnings {
+		g.warn("%s", msg)
+	}
+}
+
+// header emits the syntax, package, imports and file options
+func (g *generator) header() string {
+	var h strings.Builder
+	// trace the output back to the spec it came from; titles are one line
+	if info := g.doc.Info; info != nil {
+		if title := strings.Join(strings.Fields(info.Title), " "); title != "" {
+			writeComment(&h, "", "API: "+title)
+		}
+		if version := strings.Join(strings.Fields(info.Version), " "); version != "" {
+			writeComment(&h, "", "Version: "+version)
+		}
+	}
+	h.WriteString("// Generated by openapi-proto-transfer.\n\n")
+	h.WriteString("syntax = \"proto3\";\n\n")
+	pkg := g.opts.PackageName
+	if pkg == "" && g.doc.Info != nil {
+		pkg = packageName(g.doc.Info.Title)
+	}
+	if pkg == "" {
+		pkg = "generated"
+	}
+	h.WriteString("package " + pkg + ";\n")
+	if len(g.doc.Servers) > 0 {
+		h.WriteString("\n// Servers:\n")
+		for _, server := range g.doc.Servers {
+			line := server.URL
+			if d := strings.Join(strings.Fields(server.Description), " "); d != "" {
+				line += " (" + d + ")"
+			}
+			writeComment(&h, "", line)
+		}
+		h.WriteString("\n")
+	}
+	// only the imports something above actually referenced
+	for _, imp := range slices.Sorted(maps.Keys(g.imports)) {
+		h.WriteString(fmt.Sprintf("import \"%s\";\n", imp))
+	}
+	if g.opts.GoPackage != "" {
+		h.WriteString(fmt.Sprintf("\noption go_package = \"%s\";\n", g.opts.GoPackage))
+	}
+	h.WriteString("\n")
+	return h.String()
+}
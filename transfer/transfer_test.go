@@ -0,0 +1,351 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestSchemaOutputIsDeterministic(t *testing.T) {
+	spec := specHeader + `paths: {}
+components:
+  schemas:
+    Zebra:
+      type: object
+      properties:
+        stripes: {type: integer}
+        name: {type: string}
+        age: {type: integer}
+    Apple:
+      type: object
+      properties:
+        color: {type: string, enum: [red, green]}
+        weight: {type: number}
+        bitten: {type: boolean}
+    Mango:
+      type: string
+      enum: [ripe, raw]
+`
+	first := generate(t, spec, Options{})
+	for i := 0; i < 20; i++ {
+		if out := generate(t, spec, Options{}); out != first {
+			t.Fatalf("run %d differs from the first run:\n%s\nvs\n%s", i+2, out, first)
+		}
+	}
+	// schemas and fields follow sorted order
+	assertContains(t, first, "message Apple {", "bitten = 1;", "color = 2;", "weight = 3;")
+	if strings.Index(first, "message Apple") > strings.Index(first, "message Zebra") {
+		t.Errorf("schemas are not sorted:\n%s", first)
+	}
+}
+
+func TestServiceOutputIsDeterministic(t *testing.T) {
+	spec := specHeader + `paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200": {description: ok}
+    post:
+      operationId: createUser
+      responses:
+        "201": {description: created}
+  /orders:
+    get:
+      operationId: listOrders
+      responses:
+        "200": {description: ok}
+        "201": {description: ok}
+        "202": {description: ok}
+    delete:
+      operationId: deleteOrders
+      responses:
+        "204": {description: ok}
+components:
+  schemas: {}
+`
+	first := generate(t, spec, Options{})
+	for i := 0; i < 20; i++ {
+		if out := generate(t, spec, Options{}); out != first {
+			t.Fatalf("run %d differs from the first run:\n%s\nvs\n%s", i+2, out, first)
+		}
+	}
+	// paths sort lexically and methods by name
+	order := []string{"rpc DeleteOrders(", "rpc ListOrders(", "rpc ListUsers(", "rpc CreateUser("}
+	for i := 1; i < len(order); i++ {
+		if strings.Index(first, order[i-1]) > strings.Index(first, order[i]) {
+			t.Errorf("%s comes after %s:\n%s", order[i-1], order[i], first)
+		}
+	}
+}
+
+// loadSpec parses an inline YAML spec, failing the test on invalid input
+func loadSpec(t testing.TB, spec string) *openapi3.T {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("validate spec: %v", err)
+	}
+	return doc
+}
+
+// generate runs Generate over an inline spec
+func generate(t testing.TB, spec string, opts Options) string {
+	t.Helper()
+	out, err := Generate(loadSpec(t, spec), opts)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return out
+}
+
+// assertContains fails for every want line missing from the output
+func assertContains(t *testing.T, out string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("output is missing %q:\n%s", w, out)
+		}
+	}
+}
+
+// propertySpec returns a spec whose only schema, Thing, has one property
+// named value with the given inline schema
+func propertySpec(schema string) string {
+	return specHeader + `paths: {}
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        value: ` + schema + "\n"
+}
+
+// specHeader starts the inline specs used by the tests
+const specHeader = `openapi: 3.0.3
+info: {title: Test, version: "1"}
+`
+
+func TestPackageNameOption(t *testing.T) {
+	spec := specHeader + "paths: {}\ncomponents: {schemas: {}}\n"
+	assertContains(t, generate(t, spec, Options{PackageName: "shop.v1"}), "\npackage shop.v1;\n")
+}
+
+func TestGoPackageOption(t *testing.T) {
+	spec := specHeader + "paths: {}\ncomponents: {schemas: {}}\n"
+	out := generate(t, spec, Options{GoPackage: "example.com/shop/v1;shopv1"})
+	if n := strings.Count(out, "option go_package"); n != 1 {
+		t.Errorf("option go_package appears %d times, want 1:\n%s", n, out)
+	}
+	assertContains(t, out, `option go_package = "example.com/shop/v1;shopv1";`)
+	if strings.Contains(generate(t, spec, Options{}), "go_package") {
+		t.Error("option go_package emitted without GoPackage")
+	}
+	if _, err := Generate(loadSpec(t, spec), Options{GoPackage: "not a path"}); err == nil {
+		t.Error("Generate accepted an invalid go_package")
+	}
+}
+
+// generateUnvalidated runs Generate over an inline spec without validating
+// it, since kin-openapi's validator rejects some 3.1 keywords
+func generateUnvalidated(t *testing.T, spec string, opts Options) string {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	out, err := Generate(doc, opts)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return out
+}
+
+func TestOnlyUsedImports(t *testing.T) {
+	out := generate(t, propertySpec("{type: string}"), DefaultOptions())
+	if strings.Contains(out, "import ") {
+		t.Errorf("spec using no well-known types has imports:\n%s", out)
+	}
+}
+
+// syntheticSpec builds a spec with n component schemas, each with a few
+// scalar, enum and reference properties, and a CRUD path per schema
+func syntheticSpec(n int) string {
+	var b strings.Builder
+	b.WriteString(specHeader + "paths:\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `  /things%[1]d/{id}:
+    get:
+      operationId: getThing%[1]d
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+        - {name: verbose, in: query, schema: {type: boolean}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Thing%[1]d'}
+    put:
+      operationId: putThing%[1]d
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Thing%[1]d'}
+      responses:
+        "204": {description: ok}
+`, i)
+	}
+	b.WriteString("components:\n  schemas:\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `    Thing%[1]d:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string, format: uuid}
+        count: {type: integer, format: int64, minimum: 0}
+        ratio: {type: number, maximum: 1}
+        state: {type: string, enum: [on, off]}
+        tags: {type: array, items: {type: string}}
+        next: {$ref: '#/components/schemas/Thing%[2]d'}
+`, i, (i+1)%n)
+	}
+	return b.String()
+}
+
+func TestParallelOutputMatchesSerial(t *testing.T) {
+	doc := loadSpec(t, syntheticSpec(200))
+	serial, err := Generate(doc, Options{Workers: 1, EmitHTTPAnnotations: true})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, workers := range []int{2, 8} {
+		parallel, err := Generate(doc, Options{Workers: workers, EmitHTTPAnnotations: true})
+		if err != nil {
+			t.Fatalf("Generate with %d workers: %v", workers, err)
+		}
+		if parallel != serial {
+			t.Errorf("output with %d workers differs from the serial output", workers)
+		}
+	}
+}
+func BenchmarkWriteSchemas(b *testing.B) {
+	doc := loadSpec(b, syntheticSpec(500))
+	names := slices.Sorted(maps.Keys(doc.Components.Schemas))
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				newGenerator(doc, Options{Workers: workers}).writeSchemas(names)
+			}
+		})
+	}
+}
+
+func TestIndentOption(t *testing.T) {
+	spec := specHeader + `paths:
+  /jobs/{id}:
+    get:
+      operationId: getJob
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Job'}
+components:
+  schemas:
+    Job:
+      type: object
+      properties:
+        state: {type: string, enum: [queued, done]}
+        spec:
+          type: object
+          properties:
+            image: {type: string}
+`
+	spaces := generate(t, spec, DefaultOptions())
+	opts := DefaultOptions()
+	opts.Indent = "\t"
+	tabs := generate(t, spec, opts)
+	assertContains(t, tabs,
+		"\tenum StateEnum {\n\t\tSTATE_ENUM_UNSPECIFIED = 0;\n",
+		"\tmessage Spec {\n\t\toptional string image = 1;\n\t}",
+		"\t// GET /jobs/{id} -> 200\n\trpc ",
+		"\t\toption (google.api.http) = {\n\t\t\tget: \"/jobs/{id}\"\n\t\t};\n\t}",
+	)
+	// the tab output is the two-space output with each indent level swapped
+	lines := strings.Split(spaces, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		lines[i] = strings.Repeat("\t", (len(line)-len(trimmed))/2) + trimmed
+	}
+	if want := strings.Join(lines, "\n"); tabs != want {
+		t.Errorf("tab output:\n%s\nwant:\n%s", tabs, want)
+	}
+}
+
+func TestNilPathsAndComponents(t *testing.T) {
+	info := &openapi3.Info{Title: "Test", Version: "1"}
+	schemasOnly := &openapi3.T{OpenAPI: "3.0.3", Info: info, Components: &openapi3.Components{
+		Schemas: openapi3.Schemas{"User": openapi3.NewObjectSchema().WithProperty("id", openapi3.NewStringSchema()).NewRef()},
+	}}
+	pathsOnly := loadSpec(t, specHeader+`paths:
+  /ping:
+    get:
+      operationId: ping
+      responses:
+        "204": {description: ok}
+`)
+	tests := []struct {
+		name string
+		doc  *openapi3.T
+		want []string
+	}{
+		{"schemas only", schemasOnly, []string{"message User {\n  optional string id = 1;\n}", "service ApiService {\n}"}},
+		{"paths only", pathsOnly, []string{"rpc Ping(google.protobuf.Empty) returns (google.protobuf.Empty);"}},
+		{"neither", &openapi3.T{OpenAPI: "3.0.3", Info: info}, []string{"service ApiService {\n}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Generate(tt.doc, Options{})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			assertContains(t, out, tt.want...)
+		})
+	}
+}
+
+func TestServersHeader(t *testing.T) {
+	out := generate(t, specHeader+`servers:
+  - {url: "https://api.example.com", description: Production}
+  - {url: "https://staging.example.com"}
+paths: {}
+components: {schemas: {}}
+`, DefaultOptions())
+	assertContains(t, out, "// Servers:\n// https://api.example.com (Production)\n// https://staging.example.com\n")
+}
+
+func TestTitleAndVersionHeader(t *testing.T) {
+	out := generate(t, `openapi: 3.0.3
+info: {title: Pet Store, version: "2.1"}
+paths: {}
+components: {schemas: {}}
+`, DefaultOptions())
+	if !strings.HasPrefix(out, "// API: Pet Store\n// Version: 2.1\n") {
+		t.Errorf("header does not start with the title and version:\n%s", out)
+	}
+}
@@ -0,0 +1,344 @@
+package transfer
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func (g *generator) mapType(field string, ref *openapi3.SchemaRef) string {
+	if t, ok := g.protoTypeOverride(ref.Value); ok {
+		return t
+	}
+	if ref.Ref != "" && definesType(ref.Value) {
+		return typeName(refName(ref.Ref))
+	}
+	if ref.Ref != "" {
+		// components with no message or enum of their own map like the
+		// schema they stand for; a component that is its own item stops
+		// at Value
+		if g.inlining[ref.Value] {
+			return "google.protobuf.Value"
+		}
+		g.inlining[ref.Value] = true
+		defer delete(g.inlining, ref.Value)
+		return g.mapType(field, &openapi3.SchemaRef{Value: ref.Value})
+	}
+	if alias := refAlias(ref); alias != nil {
+		return g.mapType(field, alias)
+	}
+	s := ref.Value
+	if len(enumValues(s)) > 0 {
+		if shared := g.sharedEnum(s); shared != "" {
+			return shared
+		}
+		return typeName(field) + "Enum"
+	}
+	// 3.1 type unions other than T|null have no single proto type
+	if len(nonNullTypes(s)) > 1 {
+		return "google.protobuf.Value"
+	}
+	tp := schemaType(s)
+	if tp == "" && (s.AdditionalProperties.Schema != nil || s.AdditionalProperties.Has != nil) {
+		tp = "object"
+	}
+	if custom, ok := g.opts.TypeMap[tp+"/"+s.Format]; ok {
+		return custom
+	}
+	switch tp {
+	case "integer":
+		unsigned := g.opts.UnsignedFromMinimum && nonNegative(s)
+		if s.Format == "int64" {
+			if g.opts.Int64AsString {
+				return "string"
+			}
+			if unsigned {
+				return "uint64"
+			}
+			return "int64"
+		}
+		if unsigned {
+			return "uint32"
+		}
+		return "int32"
+	case "number":
+		if s.Format == "float" {
+			return "float"
+		}
+		return "double"
+	case "boolean":
+		return "bool"
+	case "string":
+		switch s.Format {
+		case "date-time":
+			return "google.protobuf.Timestamp"
+		case "byte", "binary":
+			return "bytes"
+		}
+		return "string"
+	case "array":
+		if s.Items != nil {
+			return "repeated " + repeatedItem(g.mapType(field, s.Items))
+		}
+	case "object":
+		if ap := s.AdditionalProperties.Schema; ap != nil {
+			v := g.mapType(field, ap)
+			// map values cannot be repeated or maps themselves
+			if strings.HasPrefix(v, "repeated ") || strings.HasPrefix(v, "map<") {
+				return "google.protobuf.Struct"
+			}
+			return "map<string, " + v + ">"
+		}
+		// free-form objects keep arbitrary nested JSON
+		return "google.protobuf.Struct"
+	}
+	if tp == "" && isEmptySchema(s) {
+		return "google.protobuf.Value"
+	}
+	g.warn("%s.%s: no proto type for schema type %q, using string", g.scope, field, tp)
+	return "string"
+}
+
+// wellKnownImports maps well-known types to the file that defines them
+var wellKnownImports = map[string]string{
+	"google.protobuf.Empty":       "google/protobuf/empty.proto",
+	"google.protobuf.FieldMask":   "google/protobuf/field_mask.proto",
+	"google.protobuf.Struct":      "google/protobuf/struct.proto",
+	"google.protobuf.Value":       "google/protobuf/struct.proto",
+	"google.protobuf.ListValue":   "google/protobuf/struct.proto",
+	"google.protobuf.Timestamp":   "google/protobuf/timestamp.proto",
+	"google.protobuf.StringValue": "google/protobuf/wrappers.proto",
+	"google.protobuf.BoolValue":   "google/protobuf/wrappers.proto",
+	"google.protobuf.Int32Value":  "google/protobuf/wrappers.proto",
+	"google.protobuf.Int64Value":  "google/protobuf/wrappers.proto",
+	"google.protobuf.UInt32Value": "google/protobuf/wrappers.proto",
+	"google.protobuf.UInt64Value": "google/protobuf/wrappers.proto",
+	"google.protobuf.FloatValue":  "google/protobuf/wrappers.proto",
+	"google.protobuf.DoubleValue": "google/protobuf/wrappers.proto",
+	"google.protobuf.BytesValue":  "google/protobuf/wrappers.proto",
+}
+
+// scalarWrappers maps scalar types to the well-known message wrapping
+// them, for places such as a stream that only take messages
+var scalarWrappers = map[string]string{
+	"string": "google.protobuf.StringValue",
+	"bool":   "google.protobuf.BoolValue",
+	"int32":  "google.protobuf.Int32Value",
+	"int64":  "google.protobuf.Int64Value",
+	"uint32": "google.protobuf.UInt32Value",
+	"uint64": "google.protobuf.UInt64Value",
+	"float":  "google.protobuf.FloatValue",
+	"double": "google.protobuf.DoubleValue",
+	"bytes":  "google.protobuf.BytesValue",
+}
+
+var typeTokenPattern = regexp.MustCompile(`[A-Za-z_][\w.]*`)
+
+// use records the imports needed by the types referenced in a field or rpc
+// type, such as "repeated google.protobuf.Timestamp": well-known types and,
+// in split output, types defined in another file. It returns t unchanged.
+func (g *generator) use(t string) string {
+	for _, tok := range typeTokenPattern.FindAllString(t, -1) {
+		if imp, ok := wellKnownImports[tok]; ok {
+			g.imports[imp] = true
+			continue
+		}
+		file, ok := g.schemaFiles[tok]
+		if !ok {
+			file, ok = g.generated[tok]
+		}
+		if ok && file != "" && file != g.file {
+			g.imports[file] = true
+		}
+	}
+	return t
+}
+
+// nonNullTypes returns the schema's declared types without "null"
+func nonNullTypes(s *openapi3.Schema) []string {
+	var types []string
+	for _, t := range s.Type.Slice() {
+		if t != openapi3.TypeNull {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// schemaType returns the single non-null type of a schema, or "" when it
+// declares none or several
+func schemaType(s *openapi3.Schema) string {
+	if types := nonNullTypes(s); len(types) == 1 {
+		return types[0]
+	}
+	return ""
+}
+
+// nullable reports whether a schema admits null, via the 3.0 nullable
+// keyword or a 3.1 "null" type
+func nullable(s *openapi3.Schema) bool {
+	return s.Nullable || s.Type.Includes(openapi3.TypeNull)
+}
+
+// isEmptySchema reports whether a schema is {} and so accepts any JSON value
+func isEmptySchema(s *openapi3.Schema) bool {
+	return len(s.Type.Slice()) == 0 && s.Format == "" && len(enumValues(s)) == 0 &&
+		len(s.Properties) == 0 && s.Items == nil &&
+		s.AdditionalProperties.Has == nil && s.AdditionalProperties.Schema == nil &&
+		len(s.OneOf) == 0 && len(s.AnyOf) == 0 && len(s.AllOf) == 0 && s.Not == nil
+}
+
+// nonNegative reports whether an integer schema's lower bound rules out
+// negative values
+func nonNegative(s *openapi3.Schema) bool {
+	if s.Min == nil {
+		return false
+	}
+	if s.ExclusiveMin {
+		return *s.Min >= -1
+	}
+	return *s.Min >= 0
+}
+
+// resolveType picks the RPC input/output type for a body schema, kind being
+// "Request" or "Response". Inline objects become a message called
+// <rpc><kind>; arrays are wrapped in a List<Items><kind> message shared by
+// every rpc with the same item type, since RPCs cannot use a bare repeated
+// value.
+func (g *generator) resolveType(rpc, kind string, ref *openapi3.SchemaRef) string {
+	name := rpc + kind
+	if t, ok := g.protoTypeOverride(ref.Value); ok {
+		return t
+	}
+	if ref.Ref != "" && definesType(ref.Value) {
+		return typeName(refName(ref.Ref))
+	}
+	if ref.Ref != "" {
+		return g.resolveType(rpc, kind, &openapi3.SchemaRef{Value: ref.Value})
+	}
+	if alias := refAlias(ref); alias != nil {
+		return g.resolveType(rpc, kind, alias)
+	}
+	s := ref.Value
+	if s == nil {
+		return "google.protobuf.Empty"
+	}
+	if schemaType(s) == "array" && s.Items != nil {
+		item := g.arrayItem(name, s)
+		parts := strings.Split(item, ".")
+		wrapper := "List" + plural(typeName(parts[len(parts)-1])) + kind
+		g.define(wrapper, func(t *generator) {
+			t.extra.WriteString(fmt.Sprintf("message %s {\n%srepeated %s items = 1;\n}\n", wrapper, t.indent, t.use(item)))
+		})
+		return wrapper
+	}
+	if isMessage(s) {
+		return g.addMessage(name, s)
+	}
+	if schemaType(s) == "object" {
+		return "google.protobuf.Struct"
+	}
+	// scalars travel in their well-known wrapper message
+	if w, ok := scalarWrappers[g.mapType(name, ref)]; ok {
+		return w
+	}
+	return "google.protobuf.Empty"
+}
+
+// definesType reports whether writeSchema emits a message or enum for the
+// component schema s, so a $ref to it can use the component name. An
+// unresolved reference is assumed to.
+func definesType(s *openapi3.Schema) bool {
+	return s == nil || len(enumValues(s)) > 0 || isMessage(s)
+}
+
+// arrayItem resolves the item type of array s; inline objects become a
+// <name>Item message
+func (g *generator) arrayItem(name string, s *openapi3.Schema) string {
+	if obj, _ := inlineObject(s.Items); obj != nil {
+		return g.addMessage(name+"Item", obj)
+	}
+	return repeatedItem(g.mapType(name, s.Items))
+}
+
+// repeatedItem returns the element type of a repeated field holding items
+// of type t: repeated fields cannot hold lists or maps, so nested arrays
+// become google.protobuf.ListValue and maps google.protobuf.Struct
+func repeatedItem(t string) string {
+	switch {
+	case strings.HasPrefix(t, "repeated "):
+		return "google.protobuf.ListValue"
+	case strings.HasPrefix(t, "map<"):
+		return "google.protobuf.Struct"
+	}
+	return t
+}
+
+// protoTypeOverride returns the type forced by an x-proto-type extension,
+// recording the file named by x-proto-import if there is one
+func (g *generator) protoTypeOverride(s *openapi3.Schema) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	t, ok := s.Extensions["x-proto-type"].(string)
+	if !ok || t == "" {
+		return "", false
+	}
+	if imp, ok := s.Extensions["x-proto-import"].(string); ok && imp != "" {
+		g.imports[imp] = true
+	}
+	return t, true
+}
+
+// componentType returns the component schema writeSchema emits as the
+// top-level type name, if any
+func (g *generator) componentType(name string) (string, bool) {
+	if !g.types[name] {
+		return "", false
+	}
+	for _, schema := range slices.Sorted(maps.Keys(g.doc.Components.Schemas)) {
+		s := g.doc.Components.Schemas[schema].Value
+		if typeName(schema) != name || s == nil || g.skipped[schema] {
+			continue
+		}
+		if _, ok := s.Extensions["x-proto-type"].(string); ok {
+			continue
+		}
+		if len(enumValues(s)) > 0 || isMessage(s) {
+			return schema, true
+		}
+	}
+	return "", false
+}
+
+// addMessage emits a synthesized top-level message once and returns its name
+func (g *generator) addMessage(name string, schema *openapi3.Schema) string {
+	name = typeName(name)
+	g.define(name, func(t *generator) { t.writeMessage(&t.extra, name, schema, "") })
+	return name
+}
+
+// define emits a synthesized top-level type once, calling write with the
+// generator of the file it belongs to: in split output, a type more than
+// one file uses is written to common.proto. Names a component schema
+// already emits are an error rather than a duplicate definition.
+func (g *generator) define(name string, write func(t *generator)) {
+	if _, ok := g.generated[name]; ok {
+		return
+	}
+	if schema, ok := g.componentType(name); ok {
+		g.fail(fmt.Errorf("%s: schema %q and the rpc's synthesized message both map to proto type %s", g.operation, schema, name))
+		return
+	}
+	target := g
+	if file, ok := g.synthFiles[name]; ok && file != g.file && g.common != nil {
+		target = g.common
+	}
+	g.generated[name] = target.file
+	write(target)
+	target.extra.WriteString("\n")
+}
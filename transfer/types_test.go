@@ -0,0 +1,285 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestIntegerFormats(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{"{type: integer, format: int64}", " int64 value = 1;"},
+		{"{type: integer, format: int32}", " int32 value = 1;"},
+		{"{type: integer}", " int32 value = 1;"},
+		{"{type: array, items: {type: integer, format: int64}}", "repeated int64 value = 1;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			assertContains(t, generate(t, propertySpec(tt.schema), Options{}), tt.want)
+		})
+	}
+}
+
+func TestNumberFormats(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{"{type: number, format: float}", " float value = 1;"},
+		{"{type: number, format: double}", " double value = 1;"},
+		{"{type: number}", " double value = 1;"},
+		{"{type: array, items: {type: number, format: float}}", "repeated float value = 1;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			assertContains(t, generate(t, propertySpec(tt.schema), Options{}), tt.want)
+		})
+	}
+}
+
+func TestDateTimeImportsTimestamp(t *testing.T) {
+	out := generate(t, propertySpec("{type: string, format: date-time}"), Options{})
+	assertContains(t, out,
+		`import "google/protobuf/timestamp.proto";`,
+		"google.protobuf.Timestamp value = 1;",
+	)
+	// the import is only added when a field needs it
+	if out := generate(t, propertySpec("{type: string}"), Options{}); strings.Contains(out, "timestamp.proto") {
+		t.Errorf("timestamp.proto imported without a date-time field:\n%s", out)
+	}
+}
+
+func TestBinaryStrings(t *testing.T) {
+	for _, format := range []string{"byte", "binary"} {
+		t.Run(format, func(t *testing.T) {
+			out := generate(t, propertySpec("{type: string, format: "+format+"}"), Options{})
+			assertContains(t, out, " bytes value = 1;")
+		})
+	}
+}
+
+func TestAdditionalPropertiesMaps(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{"{type: object, additionalProperties: {type: integer}}", "map<string, int32> value = 1;"},
+		{"{type: object, additionalProperties: {$ref: '#/components/schemas/Thing'}}", "map<string, Thing> value = 1;"},
+		{"{type: object, additionalProperties: true}", "google.protobuf.Struct value = 1;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			assertContains(t, generate(t, propertySpec(tt.schema), Options{}), tt.want)
+		})
+	}
+}
+
+func TestResolveBodies(t *testing.T) {
+	out := generate(t, specHeader+`paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/User'}}
+    post:
+      operationId: createUser
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name: {type: string}
+      responses:
+        "201":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+components:
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+`, Options{})
+	assertContains(t, out,
+		"message ListUsersResponse {\n  repeated User items = 1;\n}",
+		"message CreateUserRequest {\n  optional string name = 1;\n}",
+		"rpc ListUsers(google.protobuf.Empty) returns (ListUsersResponse)",
+		"rpc CreateUser(CreateUserRequest) returns (User)",
+	)
+}
+
+func TestUnsignedFromMinimum(t *testing.T) {
+	opts := DefaultOptions()
+	opts.UnsignedFromMinimum = true
+	out := generate(t, propertySpec("{type: integer, minimum: 0}"), opts)
+	assertContains(t, out, "optional uint32 value = 1;")
+	out = generate(t, propertySpec("{type: integer, format: int64, minimum: 0}"), opts)
+	assertContains(t, out, "optional uint64 value = 1;")
+}
+
+func TestTypeArrays(t *testing.T) {
+	out := generateUnvalidated(t, `openapi: 3.1.0
+info: {title: Test, version: "1"}
+paths: {}
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        nick: {type: [string, "null"]}
+        mixed: {type: [integer, string]}
+`, DefaultOptions())
+	assertContains(t, out,
+		"optional google.protobuf.Value mixed = 1;",
+		"optional string nick = 2;",
+	)
+}
+
+func TestFreeFormObject(t *testing.T) {
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        any: {}
+        meta: {type: object}
+`, DefaultOptions())
+	assertContains(t, out,
+		`import "google/protobuf/struct.proto";`,
+		"optional google.protobuf.Value any = 1;",
+		"optional google.protobuf.Struct meta = 2;",
+	)
+}
+
+func TestXProtoType(t *testing.T) {
+	out := generate(t, propertySpec("{type: string, x-proto-type: MyCustom.Type, x-proto-import: my/custom.proto}"), DefaultOptions())
+	assertContains(t, out,
+		`import "my/custom.proto";`,
+		"optional MyCustom.Type value = 1;",
+	)
+}
+
+func TestWarnUnmappedType(t *testing.T) {
+	var warnings []string
+	opts := DefaultOptions()
+	opts.Warn = func(msg string) { warnings = append(warnings, msg) }
+	out := generateUnvalidated(t, specHeader+`paths: {}
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        value: {type: file}
+`, opts)
+	assertContains(t, out, "optional string value = 1;")
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Thing.value") {
+		t.Errorf("warnings = %q, want one naming Thing.value", warnings)
+	}
+}
+
+func TestRefToComponentWithoutMessage(t *testing.T) {
+	spec := specHeader + `paths:
+  /meta:
+    get:
+      operationId: getMeta
+      responses: {"200": {description: ok, content: {application/json: {schema: {$ref: '#/components/schemas/Meta'}}}}}
+  /tags:
+    get:
+      operationId: getTags
+      responses: {"200": {description: ok, content: {application/json: {schema: {$ref: '#/components/schemas/Tags'}}}}}
+  /id:
+    get:
+      operationId: getId
+      responses: {"200": {description: ok, content: {application/json: {schema: {$ref: '#/components/schemas/UserId'}}}}}
+components:
+  schemas:
+    Meta: {type: object}
+    Tags: {type: array, items: {type: string}}
+    UserId: {type: string}
+    User:
+      type: object
+      properties:
+        meta: {$ref: '#/components/schemas/Meta'}
+        tags: {$ref: '#/components/schemas/Tags'}
+        id: {$ref: '#/components/schemas/UserId'}
+`
+	out := generate(t, spec, Options{})
+	assertContains(t, out,
+		"optional google.protobuf.Struct meta = 2;",
+		"repeated string tags = 3;",
+		"optional string id = 1;",
+		"rpc GetMeta(google.protobuf.Empty) returns (google.protobuf.Struct);",
+		"rpc GetTags(google.protobuf.Empty) returns (ListStringsResponse);",
+		"rpc GetId(google.protobuf.Empty) returns (google.protobuf.StringValue);",
+	)
+	for _, undefined := range []string{" Meta ", " Tags ", " UserId ", "(Meta)", "(Tags)", "(UserId)"} {
+		if strings.Contains(out, undefined) {
+			t.Errorf("output references undefined type %q:\n%s", undefined, out)
+		}
+	}
+}
+
+func TestConstPropertyIsSingleMemberEnum(t *testing.T) {
+	// kin-openapi's validator rejects const as an unknown sibling field
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`openapi: 3.1.0
+info: {title: Test, version: "1"}
+paths: {}
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        d: {const: fixed}
+`))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	out, err := Generate(doc, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	assertContains(t, out,
+		"  enum DEnum {\n    D_ENUM_UNSPECIFIED = 0;\n    D_ENUM_FIXED = 1;\n  }",
+		"optional DEnum d = 1;",
+	)
+}
+
+func TestNestedArrays(t *testing.T) {
+	spec := specHeader + `paths:
+  /grid:
+    get:
+      operationId: getGrid
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {type: array, items: {type: integer}}}
+components:
+  schemas:
+    Grid:
+      type: object
+      properties:
+        cells: {type: array, items: {type: array, items: {type: string}}}
+        rows: {type: array, items: {type: object, additionalProperties: {type: string}}}
+`
+	out := generate(t, spec, Options{})
+	assertContains(t, out,
+		`import "google/protobuf/struct.proto";`,
+		"repeated google.protobuf.ListValue cells = 1;",
+		"repeated google.protobuf.Struct rows = 2;",
+		"message ListListValuesResponse {\n  repeated google.protobuf.ListValue items = 1;\n}",
+	)
+	if strings.Contains(out, "repeated repeated") {
+		t.Errorf("nested repeated field:\n%s", out)
+	}
+}
@@ -0,0 +1,142 @@
+package transfer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// numericRuleTypes are the proto scalars that take numeric rules
+var numericRuleTypes = map[string]bool{
+	"int32": true, "int64": true, "uint32": true, "uint64": true,
+	"float": true, "double": true,
+}
+
+// validateBackend is one way of spelling extracted constraints as field
+// options
+type validateBackend struct {
+	// option is the extension the rules hang off
+	option string
+	// required is the option marking a message field as mandatory
+	required string
+	// file defines the option
+	file string
+}
+
+var (
+	pgvBackend = validateBackend{
+		option:   "(validate.rules)",
+		required: "(validate.rules).message.required = true",
+		file:     "validate/validate.proto",
+	}
+	protovalidateBackend = validateBackend{
+		option:   "(buf.validate.field)",
+		required: "(buf.validate.field).required = true",
+		file:     "buf/validate/validate.proto",
+	}
+)
+
+// fieldRules returns the validation option for a field of proto type t,
+// or "" when the schema carries no constraint the backend can express.
+// Rules are spelled for protoc-gen-validate, or for buf's protovalidate
+// when Options.Protovalidate is set. With PGVRequired, required message
+// fields must be set and required strings and bytes non-empty.
+func (g *generator) fieldRules(t string, ref *openapi3.SchemaRef, required, message bool) string {
+	constrain := g.opts.PGV || g.opts.Protovalidate
+	if !constrain && !g.opts.PGVRequired {
+		return ""
+	}
+	backend := pgvBackend
+	if g.opts.Protovalidate {
+		backend = protovalidateBackend
+	}
+	required = required && g.opts.PGVRequired
+	if message {
+		if !required {
+			return ""
+		}
+		g.imports[backend.file] = true
+		return backend.required
+	}
+	var kind string
+	var rules []string
+	if constrain && ref.Ref == "" && ref.Value != nil {
+		kind, rules = constraints(t, ref.Value)
+	}
+	if required && (t == "string" || t == "bytes") && (ref.Value == nil || ref.Value.MinLength == 0) {
+		kind = t
+		rules = append(rules, "min_len: 1")
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+	g.imports[backend.file] = true
+	return backend.option + "." + kind + " = {" + strings.Join(rules, ", ") + "}"
+}
+
+// constraints extracts the rules for schema s mapped to proto type t,
+// returning the rule kind (the proto type, or repeated for arrays) and its
+// "name: value" entries
+func constraints(t string, s *openapi3.Schema) (string, []string) {
+	var rules []string
+	if numericRuleTypes[t] {
+		integer := t != "float" && t != "double"
+		if s.Min != nil {
+			op := "gte"
+			if s.ExclusiveMin {
+				op = "gt"
+			}
+			rules = append(rules, op+": "+ruleNumber(*s.Min, integer))
+		}
+		if s.Max != nil {
+			op := "lte"
+			if s.ExclusiveMax {
+				op = "lt"
+			}
+			rules = append(rules, op+": "+ruleNumber(*s.Max, integer))
+		}
+	}
+	if strings.HasPrefix(t, "repeated ") && schemaType(s) == "array" {
+		return "repeated", itemBounds(s)
+	}
+	if t == "string" && schemaType(s) == "string" {
+		if s.Pattern != "" {
+			rules = append(rules, "pattern: "+protoString(s.Pattern))
+		}
+		if s.MinLength > 0 {
+			rules = append(rules, "min_len: "+strconv.FormatUint(s.MinLength, 10))
+		}
+		if s.MaxLength != nil {
+			rules = append(rules, "max_len: "+strconv.FormatUint(*s.MaxLength, 10))
+		}
+	}
+	return t, rules
+}
+
+// itemBounds spells the minItems/maxItems of array s as repeated rules
+func itemBounds(s *openapi3.Schema) []string {
+	var rules []string
+	if s.MinItems > 0 {
+		rules = append(rules, "min_items: "+strconv.FormatUint(s.MinItems, 10))
+	}
+	if s.MaxItems != nil {
+		rules = append(rules, "max_items: "+strconv.FormatUint(*s.MaxItems, 10))
+	}
+	return rules
+}
+
+// protoString quotes s as a proto string literal, escaping backslashes so
+// regex escapes such as \d survive
+func protoString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// ruleNumber formats a bound, without a fraction for integer fields
+func ruleNumber(v float64, integer bool) string {
+	if integer {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,106 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestPGVNumericBounds(t *testing.T) {
+	opts := DefaultOptions()
+	opts.PGV = true
+	out := generate(t, propertySpec("{type: integer, minimum: 1, maximum: 150}"), opts)
+	assertContains(t, out,
+		`import "validate/validate.proto";`,
+		"optional int32 value = 1 [(validate.rules).int32 = {gte: 1, lte: 150}];",
+	)
+}
+
+func TestPGVStringRules(t *testing.T) {
+	opts := DefaultOptions()
+	opts.PGV = true
+	out := generate(t, propertySpec("{type: string, pattern: '^[A-Z]+$', maxLength: 8}"), opts)
+	assertContains(t, out, `optional string value = 1 [(validate.rules).string = {pattern: "^[A-Z]+$", max_len: 8}];`)
+}
+
+func TestPGVRequiredRules(t *testing.T) {
+	opts := DefaultOptions()
+	opts.PGVRequired = true
+	out := generate(t, specHeader+`paths: {}
+components:
+  schemas:
+    Cat: {type: object, properties: {meow: {type: boolean}}}
+    Pet:
+      type: object
+      required: [name, owner]
+      properties:
+        name: {type: string}
+        owner: {$ref: '#/components/schemas/Cat'}
+        nick: {type: string}
+`, opts)
+	assertContains(t, out,
+		`import "validate/validate.proto";`,
+		"string name = 1 [(validate.rules).string = {min_len: 1}];",
+		"optional string nick = 2;",
+		"Cat owner = 3 [(validate.rules).message.required = true];",
+	)
+}
+
+func TestProtovalidateRules(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Protovalidate = true
+	out := generate(t, propertySpec("{type: integer, minimum: 1, maximum: 150}"), opts)
+	assertContains(t, out,
+		`import "buf/validate/validate.proto";`,
+		"optional int32 value = 1 [(buf.validate.field).int32 = {gte: 1, lte: 150}];",
+	)
+	if strings.Contains(out, "validate.rules") {
+		t.Errorf("protovalidate output has PGV rules:\n%s", out)
+	}
+}
+
+func TestPGVExclusiveBounds(t *testing.T) {
+	opts := DefaultOptions()
+	opts.PGV = true
+	// 3.0 marks a bound exclusive with a boolean next to it
+	out := generate(t, propertySpec("{type: number, minimum: 0, exclusiveMinimum: true, maximum: 10, exclusiveMaximum: true}"), opts)
+	assertContains(t, out, "optional double value = 1 [(validate.rules).double = {gt: 0, lt: 10}];")
+	out = generate(t, propertySpec("{type: number, minimum: 0, maximum: 10}"), opts)
+	assertContains(t, out, "optional double value = 1 [(validate.rules).double = {gte: 0, lte: 10}];")
+
+	// 3.1 makes the bound itself the exclusiveMinimum/exclusiveMaximum value
+	doc, err := LoadSpec(openapi3.NewLoader(), "spec.yaml", []byte(`openapi: 3.1.0
+info: {title: Test, version: "1"}
+paths: {}
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        value: {type: number, exclusiveMinimum: 0, exclusiveMaximum: 10}
+        capped: {type: integer, exclusiveMinimum: 0, minimum: 5}
+`), nil)
+	if err != nil {
+		t.Fatalf("LoadSpec: %v", err)
+	}
+	out, err = Generate(doc, opts)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	assertContains(t, out,
+		"optional double value = 2 [(validate.rules).double = {gt: 0, lt: 10}];",
+		// the inclusive minimum is stricter, so it wins
+		"optional int32 capped = 1 [(validate.rules).int32 = {gte: 5}];",
+	)
+}
+
+func TestRepeatedItemBounds(t *testing.T) {
+	spec := propertySpec("{type: array, items: {type: string}, minItems: 1, maxItems: 5}")
+	out := generate(t, spec, DefaultOptions())
+	assertContains(t, out, "  // min items: 1, max items: 5\n  repeated string value = 1;")
+	opts := DefaultOptions()
+	opts.PGV = true
+	out = generate(t, spec, opts)
+	assertContains(t, out, "repeated string value = 1 [(validate.rules).repeated = {min_items: 1, max_items: 5}];")
+}